@@ -1,85 +1,244 @@
 package bitUtil
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/bits"
+	"sync"
 )
 
+const PAGE_SIZE = 4096
+
+// BitStream stores bits in a list of fixed-size pages, so growing it never
+// copies what was already written. Every page but the last is full and
+// never modified again.
 type BitStream struct {
-	Stream  []byte
+	Pages   [][]byte
 	NumBits uint64
 	BitPos  uint64
+
+	// the first shared pages belong to someone else and are never reused
+	shared int
+	// pages emptied by Reset, reused before allocating new ones
+	spare [][]byte
 }
 
-func (b BitStream) String() string {
-	res := fmt.Sprintln(b.Stream)
-	res += fmt.Sprintf("NumBits: %d\n", b.NumBits)
-	res += fmt.Sprintf("BitPos: %d\n", b.BitPos)
+var pagePool = sync.Pool{
+	New: func() interface{} { return new([PAGE_SIZE]byte) },
+}
+
+// FromBytes returns a BitStream holding the first numBits bits of data,
+// positioned at the start. data is not copied; its pages are shared, so it
+// must not be modified while the stream is in use.
+func FromBytes(data []byte, numBits uint64) (*BitStream, error) {
+	if numBits > uint64(len(data))*8 {
+		var err = errors.New("numBits exceeds the length of data")
+		return nil, err
+	}
+	b := &BitStream{NumBits: numBits}
+	for i := 0; i < len(data); i += PAGE_SIZE {
+		j := i + PAGE_SIZE
+		if j > len(data) {
+			j = len(data)
+		}
+		b.Pages = append(b.Pages, data[i:j:j])
+	}
+	b.shared = len(b.Pages)
+	return b, nil
+}
+
+// Snapshot returns a stream holding the bits written so far, positioned at
+// the start. The sealed pages are shared and the last page is copied, so
+// writing to either stream does not affect the other; resetting b does,
+// as its pages are then reused.
+func (b *BitStream) Snapshot() BitStream {
+	sealed := b.SealedPages()
+	res := BitStream{NumBits: b.NumBits, shared: len(sealed)}
+	res.Pages = make([][]byte, len(sealed), len(b.Pages))
+	copy(res.Pages, sealed)
+	if len(b.Pages) > len(sealed) {
+		res.Pages = append(res.Pages, append(res.newPage(), b.Pages[len(sealed)]...))
+	}
 	return res
 }
 
-func (b *BitStream) AddValueToBitStream(value uint64, bitsInValue uint64) {
-	var bitsAvailable uint64
-	// calculate the numbers of bits available in the last byte
-	if b.NumBits&0x7 == 0 {
-		bitsAvailable = 0
-	} else {
-		bitsAvailable = 8 - (b.NumBits & 0x7)
+// Reset empties the stream, keeping its pages to write to again. Anything
+// sharing them, such as a Snapshot, must no longer be in use.
+func (b *BitStream) Reset() {
+	for _, page := range b.Pages[b.shared:] {
+		if page != nil {
+			b.spare = append(b.spare, page[:0])
+		}
+	}
+	b.Pages = b.Pages[:0]
+	b.NumBits, b.BitPos, b.shared = 0, 0, 0
+}
+
+// Release empties the stream like Reset but hands its pages to a pool
+// shared by all streams, for streams that will not be written to soon.
+func (b *BitStream) Release() {
+	b.Reset()
+	for _, page := range b.spare {
+		if cap(page) == PAGE_SIZE {
+			pagePool.Put((*[PAGE_SIZE]byte)(page[:PAGE_SIZE]))
+		}
+	}
+	b.Pages, b.spare = nil, nil
+}
+
+// DiscardRead drops the full pages before the read position, so a stream
+// read as it is written does not keep growing. Their bits can no longer be
+// read and Bytes no longer returns them.
+func (b *BitStream) DiscardRead() {
+	n := int(b.BitPos / 8 / PAGE_SIZE)
+	if n > len(b.Pages)-1 {
+		n = len(b.Pages) - 1
+	}
+	// an earlier call dropped the pages below a nil one
+	for i := n - 1; i >= 0 && b.Pages[i] != nil; i-- {
+		b.Pages[i] = nil
+	}
+}
+
+func (b *BitStream) newPage() []byte {
+	if n := len(b.spare); n > 0 {
+		page := b.spare[n-1]
+		b.spare = b.spare[:n-1]
+		return page
+	}
+	return pagePool.Get().(*[PAGE_SIZE]byte)[:0]
+}
+
+func (b BitStream) String() string {
+	return fmt.Sprintf("BitStream{bytes: %d, bits: %d, pos: %d}", b.Len(), b.NumBits, b.BitPos)
+}
+
+// GoString adds an offset-annotated hex dump of the stream to String.
+func (b BitStream) GoString() string {
+	return b.String() + "\n" + hex.Dump(b.Bytes())
+}
+
+// Len returns the number of bytes in the stream.
+func (b *BitStream) Len() int {
+	if len(b.Pages) == 0 {
+		return 0
 	}
-	b.NumBits += bitsInValue
-	lastByte := len(b.Stream) - 1
+	return (len(b.Pages)-1)*PAGE_SIZE + len(b.Pages[len(b.Pages)-1])
+}
 
-	if bitsInValue <= bitsAvailable {
-		// value can be stored in the last byte
-		b.Stream[lastByte] += byte(value << (bitsAvailable - bitsInValue))
-		return
+// Bytes returns a contiguous copy of the stream.
+func (b *BitStream) Bytes() []byte {
+	res := make([]byte, 0, b.Len())
+	for _, page := range b.Pages {
+		res = append(res, page...)
 	}
+	return res
+}
 
-	bitLeft := bitsInValue
-	if bitsAvailable > 0 {
-		// fill up the last byte
-		b.Stream[lastByte] += byte(value >> (bitsInValue - bitsAvailable))
-		bitLeft -= bitsAvailable
+// SealedPages returns the full pages, which are no longer written to and can
+// be handed out while the last page keeps accepting writes.
+func (b *BitStream) SealedPages() [][]byte {
+	if len(b.Pages) == 0 {
+		return nil
 	}
+	return b.Pages[:len(b.Pages)-1]
+}
+
+func (b *BitStream) byteAt(pos uint64) byte {
+	return b.Pages[pos/PAGE_SIZE][pos%PAGE_SIZE]
+}
 
-	for bitLeft >= 8 {
-		// store every 8 bits as a byte
-		b.Stream = append(b.Stream, byte(value>>(bitLeft-8)&0xFF))
-		bitLeft -= 8
+func (b *BitStream) appendByte(v byte) {
+	last := len(b.Pages) - 1
+	if last < 0 || len(b.Pages[last]) == PAGE_SIZE {
+		b.Pages = append(b.Pages, b.newPage())
+		last++
 	}
+	b.Pages[last] = append(b.Pages[last], v)
+}
+
+func (b *BitStream) addToLastByte(v byte) {
+	last := b.Pages[len(b.Pages)-1]
+	last[len(last)-1] += v
+}
 
-	if bitLeft != 0 {
-		// store the rest of the bits in a new byte
-		b.Stream = append(b.Stream, byte(value&((1<<bitLeft)-1)<<(8-bitLeft)))
+// NextWord returns the next 64 bits of the stream left-aligned without
+// advancing BitPos. Bits beyond the end of the stream read as zero.
+func (b *BitStream) NextWord() uint64 {
+	pos := b.BitPos >> 3
+	shift := b.BitPos & 0x7
+	length := uint64(b.Len())
+	var word uint64
+	if offset := pos % PAGE_SIZE; offset+8 <= PAGE_SIZE && pos+8 <= length {
+		word = binary.BigEndian.Uint64(b.Pages[pos/PAGE_SIZE][offset:])
+	} else {
+		for i := uint64(0); i < 8; i++ {
+			word <<= 8
+			if pos+i < length {
+				word |= uint64(b.byteAt(pos + i))
+			}
+		}
+	}
+	if shift != 0 {
+		word <<= shift
+		if pos+8 < length {
+			word |= uint64(b.byteAt(pos+8)) >> (8 - shift)
+		}
 	}
+	if remaining := b.NumBits - b.BitPos; remaining < 64 {
+		// hide whatever follows NumBits in the last byte
+		word &^= ^uint64(0) >> remaining
+	}
+	return word
 }
 
-func (b *BitStream) ReadValueFromBitStream(bitsToRead uint64) (uint64, error) {
-	if b.BitPos+bitsToRead > b.NumBits {
+// Advance moves BitPos forward by n bits.
+func (b *BitStream) Advance(n uint64) error {
+	if b.BitPos+n > b.NumBits {
 		var err = errors.New("Trying to read too many bits")
-		return 0, err
+		return err
 	}
-	var res uint64
-	for i := uint64(0); i < bitsToRead; i++ {
-		res <<= 1
-		bit := uint64((b.Stream[b.BitPos>>3] >> (7 - (b.BitPos & 0x7))) & 1)
-		res += bit
-		b.BitPos++
+	b.BitPos += n
+	return nil
+}
+
+// PeekBits returns the next n (at most 64) bits without advancing BitPos.
+// Bits beyond the end of the stream read as zero.
+func (b *BitStream) PeekBits(n uint64) uint64 {
+	if n == 0 {
+		return 0
 	}
-	return res, nil
+	return b.NextWord() >> (64 - n)
 }
 
-func (b *BitStream) FindTheFirstZeroBit(limit uint64) (uint64, error) {
-	for index := uint64(0); index < limit; index++ {
-		bit, err := b.ReadValueFromBitStream(1)
-		if err != nil {
-			return 0, err
+// ReadUnary reads one bits up to and including the first zero bit and
+// returns how many one bits there were. At most max one bits are read; if
+// there are that many, no terminating zero is consumed.
+func (b *BitStream) ReadUnary(max uint64) (uint64, error) {
+	var count uint64
+	for {
+		word := b.NextWord()
+		ones := uint64(64)
+		if word != ^uint64(0) {
+			ones = Clz(^word)
+		}
+		if count+ones >= max {
+			return max, b.Advance(max - count)
 		}
-		if bit == 0 {
-			return index, nil
+		if ones < 64 {
+			return count + ones, b.Advance(ones + 1)
+		}
+		if err := b.Advance(64); err != nil {
+			return 0, err
 		}
+		count += 64
 	}
-	return limit, nil
+}
+
+func (b *BitStream) FindTheFirstZeroBit(limit uint64) (uint64, error) {
+	return b.ReadUnary(limit)
 }
 
 // Ctz counts trailing zeroes
@@ -148,3 +307,13 @@ func Clz(x uint64) uint64 {
 	n = n - (x >> 63)
 	return uint64(n)
 }
+
+// Ctz32 counts trailing zeroes of a 32-bit value
+func Ctz32(x uint32) uint64 {
+	return uint64(bits.TrailingZeros32(x))
+}
+
+// Clz32 counts leading zeroes of a 32-bit value
+func Clz32(x uint32) uint64 {
+	return uint64(bits.LeadingZeros32(x))
+}
@@ -0,0 +1,160 @@
+package bitUtil
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pageValues writes values of every width from 1 to 64 bits until the
+// stream spans several pages and returns them with their widths.
+func pageValues(b *BitStream) (values, widths []uint64) {
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := 0; b.NumBits < 3*PAGE_SIZE*8+100; i++ {
+		width := uint64(i%64 + 1)
+		x = x*6364136223846793005 + 1442695040888963407
+		v := x >> (64 - width)
+		b.AddValueToBitStream(v, width)
+		values, widths = append(values, v), append(widths, width)
+	}
+	return values, widths
+}
+
+func TestPages(t *testing.T) {
+	var b BitStream
+	values, widths := pageValues(&b)
+	if len(b.Pages) != 4 || b.Len() != int((b.NumBits+7)/8) {
+		t.Fatal(len(b.Pages), b.Len(), b.NumBits)
+	}
+	for _, page := range b.SealedPages() {
+		if len(page) != PAGE_SIZE {
+			t.Fatal(len(page))
+		}
+	}
+	if len(b.SealedPages()) != 3 {
+		t.Fatal(len(b.SealedPages()))
+	}
+	if !bytes.Equal(b.Bytes(), bytes.Join(b.Pages, nil)) {
+		t.Fatal("Bytes differs from the pages")
+	}
+	for i, v := range values {
+		pos := b.BitPos
+		word := b.NextWord()
+		if got := word >> (64 - widths[i]); got != v {
+			t.Fatalf("value %d at bit %d: NextWord has %x, want %x", i, pos, got, v)
+		}
+		got, err := b.ReadValueFromBitStream(widths[i])
+		if err != nil || got != v {
+			t.Fatalf("value %d at bit %d: read %x %v, want %x", i, pos, got, err, v)
+		}
+	}
+	if _, err := b.ReadValueFromBitStream(1); err == nil {
+		t.Fatal("read past the end")
+	}
+}
+
+func TestReadUnary(t *testing.T) {
+	var b BitStream
+	for _, ones := range []uint64{0, 1, 5, 63, 64, 65, 200} {
+		for i := uint64(0); i < ones; i++ {
+			b.AddValueToBitStream(1, 1)
+		}
+		b.AddValueToBitStream(0, 1)
+	}
+	b.AddValueToBitStream(0x1f, 5)
+
+	for _, ones := range []uint64{0, 1, 5, 63, 64, 65, 200} {
+		if peek := b.PeekBits(1); peek != 1 && ones > 0 {
+			t.Fatalf("PeekBits(1) = %d before %d ones", peek, ones)
+		}
+		pos := b.BitPos
+		if n, err := b.ReadUnary(1000); err != nil || n != ones || b.BitPos != pos+ones+1 {
+			t.Fatal(ones, n, err, b.BitPos-pos)
+		}
+	}
+	// max stops the run without consuming a terminating zero
+	if n, err := b.ReadUnary(3); err != nil || n != 3 || b.PeekBits(2) != 0x3 {
+		t.Fatal(n, err, b.PeekBits(2))
+	}
+	if b.PeekBits(0) != 0 || b.PeekBits(3) != 0x6 {
+		t.Fatal("PeekBits does not zero-fill past the end", b.PeekBits(3))
+	}
+	if _, err := b.ReadUnary(10); err == nil {
+		t.Fatal("ReadUnary ran past the end")
+	}
+}
+
+func TestResetReusesPages(t *testing.T) {
+	var b BitStream
+	pageValues(&b)
+	first := &b.Pages[0][0]
+	b.Reset()
+	if b.NumBits != 0 || b.Len() != 0 {
+		t.Fatal(b.NumBits, b.Len())
+	}
+	values, widths := pageValues(&b)
+	reused := false
+	for _, page := range b.Pages {
+		if &page[0] == first {
+			reused = true
+		}
+	}
+	if !reused {
+		t.Fatal("Reset did not keep the pages")
+	}
+	for i, v := range values {
+		if got, err := b.ReadValueFromBitStream(widths[i]); err != nil || got != v {
+			t.Fatalf("value %d after Reset: read %x %v, want %x", i, got, err, v)
+		}
+	}
+	b.Release()
+	if b.Pages != nil || b.NumBits != 0 {
+		t.Fatal("Release kept pages")
+	}
+}
+
+func TestResetKeepsSharedPages(t *testing.T) {
+	data := bytes.Repeat([]byte{0xaa}, 2*PAGE_SIZE+10)
+	b, err := FromBytes(data, uint64(len(data))*8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	pageValues(b)
+	if !bytes.Equal(data, bytes.Repeat([]byte{0xaa}, len(data))) {
+		t.Fatal("wrote into the pages passed to FromBytes")
+	}
+
+	var orig BitStream
+	pageValues(&orig)
+	want := orig.Bytes()
+	snap := orig.Snapshot()
+	snap.AddValueToBitStream(0x5555, 16)
+	snap.Reset()
+	pageValues(&snap)
+	snap.AddValueToBitStream(0x5555, 16)
+	if !bytes.Equal(orig.Bytes(), want) {
+		t.Fatal("writing to a Snapshot changed the original")
+	}
+}
+
+func TestDiscardRead(t *testing.T) {
+	var b BitStream
+	values, widths := pageValues(&b)
+	for i, v := range values {
+		if got, err := b.ReadValueFromBitStream(widths[i]); err != nil || got != v {
+			t.Fatalf("value %d: read %x %v, want %x", i, got, err, v)
+		}
+		b.DiscardRead()
+		for j, page := range b.Pages {
+			if uint64(j+1)*PAGE_SIZE*8 <= b.BitPos && j < len(b.Pages)-1 {
+				if page != nil {
+					t.Fatalf("page %d kept at bit %d", j, b.BitPos)
+				}
+			} else if page == nil {
+				t.Fatalf("page %d dropped before it was read", j)
+			}
+		}
+	}
+	b.Reset()
+	pageValues(&b)
+}
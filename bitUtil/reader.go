@@ -0,0 +1,61 @@
+package bitUtil
+
+// Reader is a read-only view of a BitStream. It shares the stream's bytes
+// without copying them, has its own read position and never reads past its
+// limit, whatever the underlying bytes contain.
+type Reader struct {
+	bs BitStream
+}
+
+// NewReader returns a Reader over the first limit bits of b, or over all of
+// b if limit is larger than b.NumBits.
+func NewReader(b *BitStream, limit uint64) *Reader {
+	if limit > b.NumBits {
+		limit = b.NumBits
+	}
+	return &Reader{bs: BitStream{Pages: b.Pages, NumBits: limit}}
+}
+
+// NewReaderFromBytes returns a Reader over the first numBits bits of data.
+// data is not copied and must not be modified while the Reader is in use.
+func NewReaderFromBytes(data []byte, numBits uint64) (*Reader, error) {
+	b, err := FromBytes(data, numBits)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{bs: *b}, nil
+}
+
+// NumBits returns the number of readable bits.
+func (r *Reader) NumBits() uint64 {
+	return r.bs.NumBits
+}
+
+// BitPos returns the current read position.
+func (r *Reader) BitPos() uint64 {
+	return r.bs.BitPos
+}
+
+func (r *Reader) ReadValueFromBitStream(bitsToRead uint64) (uint64, error) {
+	return r.bs.ReadValueFromBitStream(bitsToRead)
+}
+
+func (r *Reader) FindTheFirstZeroBit(limit uint64) (uint64, error) {
+	return r.bs.FindTheFirstZeroBit(limit)
+}
+
+func (r *Reader) NextWord() uint64 {
+	return r.bs.NextWord()
+}
+
+func (r *Reader) Advance(n uint64) error {
+	return r.bs.Advance(n)
+}
+
+func (r *Reader) PeekBits(n uint64) uint64 {
+	return r.bs.PeekBits(n)
+}
+
+func (r *Reader) ReadUnary(max uint64) (uint64, error) {
+	return r.bs.ReadUnary(max)
+}
@@ -0,0 +1,48 @@
+package bitUtil
+
+import (
+	"testing"
+)
+
+func TestReaderLimit(t *testing.T) {
+	var b BitStream
+	b.AddValueToBitStream(0x7, 3)
+	b.AddValueToBitStream(0x1ff, 9)
+
+	r := NewReader(&b, 5)
+	if r.NumBits() != 5 {
+		t.Fatal(r.NumBits())
+	}
+	if word := r.NextWord(); word != 0xf8<<56 {
+		t.Fatalf("NextWord shows bits past the limit: %x", word)
+	}
+	if _, err := r.ReadValueFromBitStream(6); err == nil {
+		t.Fatal("read past the limit")
+	}
+	if v, err := r.ReadValueFromBitStream(5); err != nil || v != 0x1f || r.BitPos() != 5 {
+		t.Fatal(v, err, r.BitPos())
+	}
+	if b.BitPos != 0 {
+		t.Fatal("Reader moved the stream's read position")
+	}
+	if NewReader(&b, 100).NumBits() != b.NumBits {
+		t.Fatal("limit not capped at NumBits")
+	}
+}
+
+func TestReaderFromBytes(t *testing.T) {
+	var b BitStream
+	values, widths := pageValues(&b)
+	r, err := NewReaderFromBytes(b.Bytes(), b.NumBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		if got, err := r.ReadValueFromBitStream(widths[i]); err != nil || got != v {
+			t.Fatalf("value %d: read %x %v, want %x", i, got, err, v)
+		}
+	}
+	if _, err := NewReaderFromBytes(make([]byte, 2), 17); err == nil {
+		t.Fatal("accepted numBits past the data")
+	}
+}
@@ -0,0 +1,66 @@
+//go:build !tsc32
+
+package bitUtil
+
+import (
+	"errors"
+)
+
+func (b *BitStream) AddValueToBitStream(value uint64, bitsInValue uint64) {
+	var bitsAvailable uint64
+	// calculate the numbers of bits available in the last byte
+	if b.NumBits&0x7 == 0 {
+		bitsAvailable = 0
+	} else {
+		bitsAvailable = 8 - (b.NumBits & 0x7)
+	}
+	b.NumBits += bitsInValue
+
+	if bitsInValue <= bitsAvailable {
+		// value can be stored in the last byte
+		b.addToLastByte(byte(value << (bitsAvailable - bitsInValue)))
+		return
+	}
+
+	bitLeft := bitsInValue
+	if bitsAvailable > 0 {
+		// fill up the last byte
+		b.addToLastByte(byte(value >> (bitsInValue - bitsAvailable)))
+		bitLeft -= bitsAvailable
+	}
+
+	for bitLeft >= 8 {
+		// store every 8 bits as a byte
+		b.appendByte(byte(value >> (bitLeft - 8) & 0xFF))
+		bitLeft -= 8
+	}
+
+	if bitLeft != 0 {
+		// store the rest of the bits in a new byte
+		b.appendByte(byte(value & ((1 << bitLeft) - 1) << (8 - bitLeft)))
+	}
+}
+
+func (b *BitStream) ReadValueFromBitStream(bitsToRead uint64) (uint64, error) {
+	if b.BitPos+bitsToRead > b.NumBits {
+		var err = errors.New("Trying to read too many bits")
+		return 0, err
+	}
+	var res uint64
+	for i := uint64(0); i < bitsToRead; i++ {
+		res <<= 1
+		bit := uint64((b.byteAt(b.BitPos>>3) >> (7 - (b.BitPos & 0x7))) & 1)
+		res += bit
+		b.BitPos++
+	}
+	return res, nil
+}
+
+func (b *BitStream) AddValue32(value uint32, bitsInValue uint64) {
+	b.AddValueToBitStream(uint64(value), bitsInValue)
+}
+
+func (b *BitStream) ReadValue32(bitsToRead uint64) (uint32, error) {
+	res, err := b.ReadValueFromBitStream(bitsToRead)
+	return uint32(res), err
+}
@@ -0,0 +1,181 @@
+// Package ring places series on store nodes with consistent hashing, so
+// adding or removing a node only moves the series it gains or loses.
+package ring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const DEFAULT_VIRTUAL_NODES = 128
+
+// Ring maps series IDs to the nodes holding their replicas. It is not safe
+// for concurrent modification; Clone it before changing a Ring in use.
+type Ring struct {
+	replicationFactor int
+	virtualNodes      int
+	nodes             map[string]bool
+	// points on the ring, sorted by hash
+	hashes []uint64
+	owners []string
+}
+
+// New returns an empty Ring placing every series on replicationFactor
+// nodes, each node taking virtualNodes points on the ring
+// (DEFAULT_VIRTUAL_NODES if virtualNodes <= 0) to even out the load.
+func New(replicationFactor, virtualNodes int) *Ring {
+	if replicationFactor < 1 {
+		replicationFactor = 1
+	}
+	if virtualNodes <= 0 {
+		virtualNodes = DEFAULT_VIRTUAL_NODES
+	}
+	return &Ring{
+		replicationFactor: replicationFactor,
+		virtualNodes:      virtualNodes,
+		nodes:             map[string]bool{},
+	}
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	// fnv alone clusters keys that differ only at the end
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Add puts node on the ring. Adding a node twice has no effect.
+func (r *Ring) Add(node string) {
+	if r.nodes[node] {
+		return
+	}
+	r.nodes[node] = true
+	for i := 0; i < r.virtualNodes; i++ {
+		r.hashes = append(r.hashes, hash(node+"#"+strconv.Itoa(i)))
+		r.owners = append(r.owners, node)
+	}
+	sort.Sort(byHash{r})
+}
+
+// Remove takes node off the ring.
+func (r *Ring) Remove(node string) {
+	if !r.nodes[node] {
+		return
+	}
+	delete(r.nodes, node)
+	hashes, owners := r.hashes[:0], r.owners[:0]
+	for i, owner := range r.owners {
+		if owner != node {
+			hashes = append(hashes, r.hashes[i])
+			owners = append(owners, owner)
+		}
+	}
+	r.hashes, r.owners = hashes, owners
+}
+
+// Clone returns an independent copy of r.
+func (r *Ring) Clone() *Ring {
+	res := New(r.replicationFactor, r.virtualNodes)
+	for node := range r.nodes {
+		res.nodes[node] = true
+	}
+	res.hashes = append([]uint64(nil), r.hashes...)
+	res.owners = append([]string(nil), r.owners...)
+	return res
+}
+
+// Len returns the number of nodes on the ring.
+func (r *Ring) Len() int {
+	return len(r.nodes)
+}
+
+// Nodes returns the nodes holding the replicas of seriesID, primary first.
+// There are fewer than the replication factor if the ring has fewer nodes.
+func (r *Ring) Nodes(seriesID string) []string {
+	n := r.replicationFactor
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	res := make([]string, 0, n)
+	if n == 0 {
+		return res
+	}
+	h := hash(seriesID)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for i := 0; len(res) < n; i++ {
+		owner := r.owners[(start+i)%len(r.owners)]
+		if !contains(res, owner) {
+			res = append(res, owner)
+		}
+	}
+	return res
+}
+
+// Move is a replica of a series that must be copied to a node when the
+// ring changes.
+type Move struct {
+	SeriesID string
+	// From is a node that held the series before, empty if none did.
+	From string
+	To   string
+}
+
+// Moves returns the copies needed for the series in seriesIDs to go from
+// their placement on before to their placement on after. Once they are
+// done, nodes holding a series they no longer own according to after can
+// drop it.
+func Moves(before, after *Ring, seriesIDs []string) []Move {
+	var moves []Move
+	for _, id := range seriesIDs {
+		from := before.Nodes(id)
+		for _, to := range after.Nodes(id) {
+			if contains(from, to) {
+				continue
+			}
+			m := Move{SeriesID: id, To: to}
+			if len(from) > 0 {
+				m.From = from[0]
+			}
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func contains(nodes []string, node string) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// byHash sorts the points of a ring, breaking ties by owner so the order
+// does not depend on the order nodes were added in.
+type byHash struct {
+	r *Ring
+}
+
+func (b byHash) Len() int {
+	return len(b.r.hashes)
+}
+
+func (b byHash) Less(i, j int) bool {
+	if b.r.hashes[i] != b.r.hashes[j] {
+		return b.r.hashes[i] < b.r.hashes[j]
+	}
+	return b.r.owners[i] < b.r.owners[j]
+}
+
+func (b byHash) Swap(i, j int) {
+	b.r.hashes[i], b.r.hashes[j] = b.r.hashes[j], b.r.hashes[i]
+	b.r.owners[i], b.r.owners[j] = b.r.owners[j], b.r.owners[i]
+}
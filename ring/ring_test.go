@@ -0,0 +1,93 @@
+package ring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func seriesIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = "cpu.host" + strconv.Itoa(i) + ".user"
+	}
+	return ids
+}
+
+func newRing(nodes ...string) *Ring {
+	r := New(3, 0)
+	for _, node := range nodes {
+		r.Add(node)
+	}
+	return r
+}
+
+func TestNodes(t *testing.T) {
+	r := newRing("a", "b", "c", "d", "e")
+	reversed := newRing("e", "d", "c", "b", "a")
+	primaries := map[string]int{}
+	ids := seriesIDs(10000)
+	for _, id := range ids {
+		nodes := r.Nodes(id)
+		if len(nodes) != 3 || nodes[0] == nodes[1] || nodes[0] == nodes[2] || nodes[1] == nodes[2] {
+			t.Fatalf("%s: %v", id, nodes)
+		}
+		other := reversed.Nodes(id)
+		for i := range nodes {
+			if nodes[i] != other[i] {
+				t.Fatalf("%s: placement depends on the order of Add: %v, %v", id, nodes, other)
+			}
+		}
+		primaries[nodes[0]]++
+	}
+	for node, n := range primaries {
+		if n < len(ids)/10 || n > len(ids)*3/10 {
+			t.Fatalf("node %s is primary for %d of %d series", node, n, len(ids))
+		}
+	}
+
+	if nodes := newRing("a", "b").Nodes("x"); len(nodes) != 2 {
+		t.Fatal("fewer nodes than the replication factor:", nodes)
+	}
+	if nodes := New(3, 0).Nodes("x"); len(nodes) != 0 {
+		t.Fatal("empty ring:", nodes)
+	}
+}
+
+func TestMovesAdd(t *testing.T) {
+	before := newRing("a", "b", "c", "d")
+	after := before.Clone()
+	after.Add("e")
+	if before.Len() != 4 || after.Len() != 5 {
+		t.Fatal("Clone shares nodes with the original")
+	}
+	ids := seriesIDs(10000)
+	moves := Moves(before, after, ids)
+	// e takes about 3/5 of the replicas it now holds from elsewhere
+	if len(moves) < len(ids)*3/10 || len(moves) > len(ids)*9/10 {
+		t.Fatalf("%d moves for %d series", len(moves), len(ids))
+	}
+	for _, m := range moves {
+		if m.To != "e" || m.From == "" || m.From == "e" {
+			t.Fatalf("adding e moved %+v", m)
+		}
+	}
+	if moves := Moves(after, after, ids); len(moves) != 0 {
+		t.Fatal("moves without a change:", len(moves))
+	}
+}
+
+func TestMovesRemove(t *testing.T) {
+	before := newRing("a", "b", "c", "d", "e")
+	after := before.Clone()
+	after.Remove("c")
+	for _, m := range Moves(before, after, seriesIDs(10000)) {
+		if m.To == "c" || !contains(before.Nodes(m.SeriesID), "c") || contains(before.Nodes(m.SeriesID), m.To) {
+			t.Fatalf("removing c moved %+v", m)
+		}
+	}
+	for _, id := range seriesIDs(10000) {
+		if contains(after.Nodes(id), "c") {
+			t.Fatalf("%s is still placed on c", id)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tsc
+
+import (
+	"math"
+)
+
+// Fill policies for grid points of an AlignedIterator without a sample.
+const (
+	FILL_NAN = iota
+	FILL_ZERO
+	FILL_PREVIOUS
+	FILL_LINEAR
+)
+
+// AlignedIterator iterates over exactly (to-from)/step points at
+// start, start+step, ... where start is from rounded down to a multiple of
+// step. Each grid point takes the value of the nearest sample within half a
+// step of it; grid points without one are filled according to fill. Like
+// Derivative, it reads from a Snapshot.
+type AlignedIterator struct {
+	r           rangeReader
+	step, start uint64
+	k, n        uint64
+	fill        int
+
+	pending    Point
+	hasPending bool
+	prev       Point
+	hasPrev    bool
+
+	timestamp uint64
+	value     float64
+}
+
+func NewAlignedIterator(s *Series, step, from, to uint64, fill int) *AlignedIterator {
+	a := &AlignedIterator{r: rangeReader{s: s.Snapshot(), to: math.MaxUint64}, step: step, fill: fill}
+	if step > 0 && to > from {
+		a.start = from - from%step
+		a.n = (to - from) / step
+	}
+	return a
+}
+
+func (a *AlignedIterator) peek() bool {
+	if !a.hasPending {
+		a.pending.Timestamp, a.pending.Value, a.hasPending = a.r.next()
+	}
+	return a.hasPending
+}
+
+func (a *AlignedIterator) Next() bool {
+	if a.k >= a.n {
+		return false
+	}
+	g := a.start + a.k*a.step
+	half := a.step / 2
+
+	var best Point
+	found := false
+	for a.peek() {
+		t := a.pending.Timestamp
+		if t+half >= g+a.step {
+			// belongs to a later grid point
+			break
+		}
+		if t+half >= g && (!found || distance(t, g) < distance(best.Timestamp, g)) {
+			best = a.pending
+			found = true
+		}
+		a.prev, a.hasPrev = a.pending, true
+		a.hasPending = false
+	}
+	if a.r.err != nil {
+		return false
+	}
+
+	a.timestamp = g
+	switch {
+	case found:
+		a.value = best.Value
+	case a.fill == FILL_ZERO:
+		a.value = 0
+	case a.fill == FILL_PREVIOUS && a.hasPrev:
+		a.value = a.prev.Value
+	case a.fill == FILL_LINEAR && a.hasPrev && a.hasPending:
+		next := a.pending
+		ratio := float64(g-a.prev.Timestamp) / float64(next.Timestamp-a.prev.Timestamp)
+		a.value = a.prev.Value + (next.Value-a.prev.Value)*ratio
+	default:
+		a.value = math.NaN()
+	}
+	a.k++
+	return true
+}
+
+func (a *AlignedIterator) At() (uint64, float64) {
+	return a.timestamp, a.value
+}
+
+func (a *AlignedIterator) Err() error {
+	return a.r.err
+}
+
+// ReadAll appends the values of the remaining grid points to dst.
+func (a *AlignedIterator) ReadAll(dst []float64) ([]float64, error) {
+	for a.Next() {
+		dst = append(dst, a.value)
+	}
+	return dst, a.Err()
+}
@@ -0,0 +1,49 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAlignedIteratorFill(t *testing.T) {
+	const t0 = 1440583200
+	// t0+60 and t0+300 are jittered; t0+120 and t0+180 have no sample
+	samples := []Point{{t0, 1}, {t0 + 65, 2}, {t0 + 240, 5}, {t0 + 280, 6}, {t0 + 302, 7}}
+	tests := []struct {
+		fill int
+		want []float64
+	}{
+		{FILL_NAN, []float64{1, 2, math.NaN(), math.NaN(), 5, 7}},
+		{FILL_ZERO, []float64{1, 2, 0, 0, 5, 7}},
+		{FILL_PREVIOUS, []float64{1, 2, 2, 2, 5, 7}},
+		{FILL_LINEAR, []float64{1, 2, 2 + 3*55.0/175, 2 + 3*115.0/175, 5, 7}},
+	}
+	for _, test := range tests {
+		var s Series
+		for _, p := range samples {
+			s.Append(p.Timestamp, p.Value)
+		}
+		// a point already read from s is still on the grid
+		s.Read()
+		a := NewAlignedIterator(&s, 60, t0+10, t0+370, test.fill)
+		for k, want := range test.want {
+			if !a.Next() {
+				t.Fatalf("fill %d: %d grid points, want %d", test.fill, k, len(test.want))
+			}
+			timestamp, value := a.At()
+			if timestamp != t0+60*uint64(k) {
+				t.Fatalf("fill %d: grid point %d at %d", test.fill, k, timestamp)
+			}
+			if value != want && !(math.IsNaN(value) && math.IsNaN(want)) &&
+				math.Abs(value-want) > 1e-9 {
+				t.Fatalf("fill %d: grid point %d is %v, want %v", test.fill, k, value, want)
+			}
+		}
+		if a.Next() || a.Err() != nil {
+			t.Fatalf("fill %d: extra grid point or error %v", test.fill, a.Err())
+		}
+		if timestamp, _, err := s.Read(); err != nil || timestamp != t0+65 {
+			t.Fatalf("fill %d: the iterator moved the read position", test.fill)
+		}
+	}
+}
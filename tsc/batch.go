@@ -0,0 +1,63 @@
+package tsc
+
+import (
+	"fmt"
+	"math"
+)
+
+// AppendBatch appends timestamps[i], values[i] for every i, as Append
+// would, and returns the number of bits added. Without transforms, report
+// by exception, constant runs, checksums or restarts the points go straight
+// to the codecs, skipping the per point checks of Append. It panics if the slices
+// differ in length.
+func (s *Series) AppendBatch(timestamps []uint64, values []float64) uint64 {
+	if len(timestamps) != len(values) {
+		panic(fmt.Sprintf("tsc: AppendBatch with %d timestamps and %d values", len(timestamps), len(values)))
+	}
+	before := s.Bs.NumBits
+	if len(s.Transforms) > 0 || s.exceptionEnabled || s.minRun > 0 || s.checksum || s.restartInterval > 0 {
+		for i, timestamp := range timestamps {
+			s.append(timestamp, values[i])
+		}
+		return s.Bs.NumBits - before
+	}
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	timeCodec, valueCodec := s.timeEncoder(), s.valueEncoder()
+	var bits uint64
+	for i, timestamp := range timestamps {
+		bits = math.Float64bits(values[i])
+		timeCodec.Append(&s.Bs, timestamp)
+		valueCodec.Append(&s.Bs, bits)
+	}
+	last := timestamps[len(timestamps)-1]
+	if s.count == 0 {
+		s.firstTime = timestamps[0]
+	}
+	s.prevTimeWrite, s.lastValueWrite = last, bits
+	s.lastInput, s.hasInput = last, true
+	s.count += uint64(len(timestamps))
+	return s.Bs.NumBits - before
+}
+
+// AppendBatch appends a batch of points, see Series.AppendBatch.
+func (e *Encoder) AppendBatch(timestamps []uint64, values []float64) uint64 {
+	return e.s.AppendBatch(timestamps, values)
+}
+
+// DecodeAll decodes every point appended so far into slices sized for
+// them up front. Like All, it reads from a Snapshot and leaves the read
+// position of s alone.
+func (s *Series) DecodeAll() (timestamps []uint64, values []float64, err error) {
+	// every point outside a constant run takes at least one bit, so a
+	// corrupt count cannot ask for more than the stream could hold
+	size := s.count
+	if size > s.Bs.NumBits {
+		size = s.Bs.NumBits
+	}
+	timestamps = make([]uint64, 0, size)
+	values = make([]float64, 0, size)
+	return s.Snapshot().ReadAppend(timestamps, values)
+}
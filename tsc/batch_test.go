@@ -0,0 +1,73 @@
+package tsc
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func batchPoints() ([]uint64, []float64) {
+	timestamps := make([]uint64, 3000)
+	values := make([]float64, 3000)
+	for i := range timestamps {
+		timestamps[i] = 1440583200 + 60*uint64(i) + uint64(i%5)
+		values[i] = math.Round(100*math.Sin(float64(i)/30)) / 4
+	}
+	return timestamps, values
+}
+
+// TestAppendBatch checks that a batch, split around single appends, gives
+// the same stream as Append, both with and without the per point checks.
+func TestAppendBatch(t *testing.T) {
+	timestamps, values := batchPoints()
+	optionSets := [][]Option{
+		nil,
+		{WithTimeCodec(TIME_CODEC_AUTO), WithValueCodec(VALUE_CODEC_CHIMP128)},
+		{WithValueCodec(VALUE_CODEC_DECIMAL)},
+		{WithPrometheusXOR()},
+		{WithChecksum()},
+		{WithConstantRuns(4)},
+	}
+	for n, opts := range optionSets {
+		want := NewSeries(opts...)
+		for i := range timestamps {
+			want.Append(timestamps[i], values[i])
+		}
+		got := NewSeries(opts...)
+		bits := got.AppendBatch(timestamps[:1000], values[:1000])
+		bits += got.Append(timestamps[1000], values[1000])
+		bits += got.AppendBatch(timestamps[1001:], values[1001:])
+		bits += got.AppendBatch(nil, nil)
+		if !bytes.Equal(got.Bs.Bytes(), want.Bs.Bytes()) || got.Bs.NumBits != want.Bs.NumBits {
+			t.Fatalf("options %d: AppendBatch differs from Append", n)
+		}
+		if bits != got.Bs.NumBits {
+			t.Fatalf("options %d: %d bits reported, %d written", n, bits, got.Bs.NumBits)
+		}
+		if got.count != want.count {
+			t.Fatalf("options %d: %d points, want %d", n, got.count, want.count)
+		}
+
+		decoded, decodedValues, err := got.DecodeAll()
+		if err != nil || len(decoded) != len(timestamps) {
+			t.Fatalf("options %d: %d points %v", n, len(decoded), err)
+		}
+		for i := range timestamps {
+			if decoded[i] != timestamps[i] || decodedValues[i] != values[i] {
+				t.Fatalf("options %d, point %d: (%d, %v)", n, i, decoded[i], decodedValues[i])
+			}
+		}
+		if timestamp, _, err := got.Read(); err != nil || timestamp != timestamps[0] {
+			t.Fatalf("options %d: DecodeAll moved the read position", n)
+		}
+	}
+}
+
+func TestAppendBatchLengths(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic")
+		}
+	}()
+	NewSeries().AppendBatch(make([]uint64, 2), make([]float64, 1))
+}
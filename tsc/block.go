@@ -0,0 +1,78 @@
+package tsc
+
+import (
+	"math"
+	"sort"
+)
+
+// DecodedBlock holds the points of a block as two parallel arrays, so
+// aggregations over long ranges are plain loops over memory instead of a
+// call per point.
+type DecodedBlock struct {
+	Timestamps []uint64
+	Values     []float64
+}
+
+// DecodeBlock decodes every point appended so far. Like All, it reads from
+// a Snapshot and leaves the read position of s alone.
+func (s *Series) DecodeBlock() (DecodedBlock, error) {
+	var b DecodedBlock
+	var err error
+	b.Timestamps, b.Values, err = s.DecodeAll()
+	return b, err
+}
+
+// Len returns the number of points.
+func (b DecodedBlock) Len() int {
+	return len(b.Timestamps)
+}
+
+// Range returns the points with timestamps in [start, end], sharing the
+// arrays of b. Timestamps must not decrease, as in any block written with
+// Append.
+func (b DecodedBlock) Range(start, end uint64) DecodedBlock {
+	from := sort.Search(len(b.Timestamps), func(i int) bool { return b.Timestamps[i] >= start })
+	to := sort.Search(len(b.Timestamps), func(i int) bool { return b.Timestamps[i] > end })
+	if to < from {
+		to = from
+	}
+	return DecodedBlock{b.Timestamps[from:to], b.Values[from:to]}
+}
+
+func (b DecodedBlock) Sum() float64 {
+	var sum float64
+	for _, v := range b.Values {
+		sum += v
+	}
+	return sum
+}
+
+// Mean returns NaN for an empty block.
+func (b DecodedBlock) Mean() float64 {
+	if len(b.Values) == 0 {
+		return math.NaN()
+	}
+	return b.Sum() / float64(len(b.Values))
+}
+
+// Min ignores NaN values and returns NaN if there are no others.
+func (b DecodedBlock) Min() float64 {
+	lowest := math.NaN()
+	for _, v := range b.Values {
+		if v < lowest || lowest != lowest {
+			lowest = v
+		}
+	}
+	return lowest
+}
+
+// Max ignores NaN values and returns NaN if there are no others.
+func (b DecodedBlock) Max() float64 {
+	highest := math.NaN()
+	for _, v := range b.Values {
+		if v > highest || highest != highest {
+			highest = v
+		}
+	}
+	return highest
+}
@@ -0,0 +1,230 @@
+package tsc
+
+import (
+	"errors"
+	"sync"
+)
+
+// DEFAULT_BUCKET_SIZE is two hours of second timestamps, as in Gorilla.
+const DEFAULT_BUCKET_SIZE = 7200
+
+var ErrTooManyPoints = errors.New("Query exceeds the point limit")
+
+// bucketBlock holds the points of windows first to bucket. A block covers
+// more than its own window when a late point is sorted into it.
+type bucketBlock struct {
+	first  uint64
+	bucket uint64
+	s      *Series
+}
+
+// BucketedTimeSeries splits a series into blocks each covering a window of
+// bucketSize timestamps, closing a block when the first point of the next
+// window arrives. Closed blocks are never appended to again, so they can be
+// stored or dropped independently.
+type BucketedTimeSeries struct {
+	bucketSize    uint64
+	opts          []Option
+	blocks        []bucketBlock
+	current       *Series
+	currentFirst  uint64
+	currentBucket uint64
+	cache         *DecodeCache
+}
+
+// NewBucketedTimeSeries returns an empty BucketedTimeSeries with windows of
+// bucketSize (DEFAULT_BUCKET_SIZE if 0) whose blocks are configured by opts.
+func NewBucketedTimeSeries(bucketSize uint64, opts ...Option) *BucketedTimeSeries {
+	if bucketSize == 0 {
+		bucketSize = DEFAULT_BUCKET_SIZE
+	}
+	return &BucketedTimeSeries{bucketSize: bucketSize, opts: opts}
+}
+
+// AppendPoint adds a point to the block of its window. It fails with
+// ErrOutOfOrder, or as the WithOutOfOrder option says, if the point is
+// older than the previous one. A late point whose window is already closed
+// but which OUT_OF_ORDER_SORT still accepts goes into the current block,
+// which then covers its window too.
+func (b *BucketedTimeSeries) AppendPoint(timestamp uint64, value float64) error {
+	bucket := timestamp / b.bucketSize
+	if b.current != nil && bucket != b.currentBucket {
+		if bucket < b.currentBucket {
+			if err := b.current.AppendChecked(timestamp, value); err != nil {
+				return err
+			}
+			b.currentFirst = min(b.currentFirst, bucket)
+			return nil
+		}
+		b.current.Flush()
+		b.blocks = append(b.blocks, bucketBlock{b.currentFirst, b.currentBucket, b.current})
+		b.current = nil
+	}
+	if b.current == nil {
+		b.current = NewSeries(b.opts...)
+		b.currentFirst = bucket
+		b.currentBucket = bucket
+	}
+	return b.current.AppendChecked(timestamp, value)
+}
+
+// SetDecodeCache makes queries keep the points of closed blocks in c, or
+// decode them every time if c is nil.
+func (b *BucketedTimeSeries) SetDecodeCache(c *DecodeCache) {
+	b.cache = c
+}
+
+// GetPointsInRange returns the points with timestamps in [start, end],
+// decoding only the blocks whose window overlaps it.
+func (b *BucketedTimeSeries) GetPointsInRange(start, end uint64) ([]Point, error) {
+	return b.getPointsInRange(start, end, 0)
+}
+
+// getPointsInRange fails with ErrTooManyPoints after limit points unless
+// limit is 0.
+func (b *BucketedTimeSeries) getPointsInRange(start, end uint64, limit int) ([]Point, error) {
+	var points []Point
+	blocks := b.blocks
+	if b.current != nil {
+		blocks = append(blocks[:len(blocks):len(blocks)], bucketBlock{b.currentFirst, b.currentBucket, b.current})
+	}
+	for _, block := range blocks {
+		if block.bucket < start/b.bucketSize || block.first > end/b.bucketSize {
+			continue
+		}
+		if b.cache != nil && block.s != b.current {
+			decoded, err := b.cache.decode(block.s)
+			if err != nil {
+				return points, err
+			}
+			decoded = decoded.Range(start, end)
+			for i, timestamp := range decoded.Timestamps {
+				if limit > 0 && len(points) == limit {
+					return points, ErrTooManyPoints
+				}
+				points = append(points, Point{timestamp, decoded.Values[i]})
+			}
+			continue
+		}
+		snap := block.s.Snapshot()
+		snap.Seek(start)
+		r := rangeReader{s: snap, from: start, to: end}
+		for {
+			timestamp, value, ok := r.next()
+			if !ok {
+				break
+			}
+			if limit > 0 && len(points) == limit {
+				return points, ErrTooManyPoints
+			}
+			points = append(points, Point{timestamp, value})
+		}
+		if r.err != nil {
+			return points, r.err
+		}
+	}
+	return points, nil
+}
+
+// BucketMap holds a BucketedTimeSeries per key and is safe for concurrent
+// use.
+type BucketMap struct {
+	bucketSize uint64
+	opts       []Option
+	mu         sync.RWMutex
+	series     map[string]*bucketEntry
+	cache      *DecodeCache
+}
+
+type bucketEntry struct {
+	mu sync.Mutex
+	s  *BucketedTimeSeries
+}
+
+// NewBucketMap returns an empty BucketMap whose series are created with
+// NewBucketedTimeSeries(bucketSize, opts...).
+func NewBucketMap(bucketSize uint64, opts ...Option) *BucketMap {
+	return &BucketMap{bucketSize: bucketSize, opts: opts, series: map[string]*bucketEntry{}}
+}
+
+// AppendPoint adds a point to the series of key, creating it if needed.
+func (m *BucketMap) AppendPoint(key string, timestamp uint64, value float64) error {
+	m.mu.RLock()
+	e, ok := m.series[key]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if e, ok = m.series[key]; !ok {
+			e = &bucketEntry{s: NewBucketedTimeSeries(m.bucketSize, m.opts...)}
+			e.s.SetDecodeCache(m.cache)
+			m.series[key] = e
+		}
+		m.mu.Unlock()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.s.AppendPoint(timestamp, value)
+}
+
+// SetDecodeCache sets the cache shared by the queries of every series, see
+// BucketedTimeSeries.SetDecodeCache.
+func (m *BucketMap) SetDecodeCache(c *DecodeCache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = c
+	for _, e := range m.series {
+		e.mu.Lock()
+		e.s.SetDecodeCache(c)
+		e.mu.Unlock()
+	}
+}
+
+// GetPointsInRange returns the points of key with timestamps in
+// [start, end], nil if there is no such series.
+func (m *BucketMap) GetPointsInRange(key string, start, end uint64) ([]Point, error) {
+	return m.getPointsInRange(key, start, end, 0)
+}
+
+func (m *BucketMap) getPointsInRange(key string, start, end uint64, limit int) ([]Point, error) {
+	m.mu.RLock()
+	e, ok := m.series[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.s.getPointsInRange(start, end, limit)
+}
+
+// Len returns the number of series.
+func (m *BucketMap) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.series)
+}
+
+// View returns a read-only view of m for code that must not append to it.
+// Each query through the view fails with ErrTooManyPoints once it has
+// returned maxPoints points, unless maxPoints is 0.
+func (m *BucketMap) View(maxPoints int) *BucketMapView {
+	return &BucketMapView{m: m, maxPoints: maxPoints}
+}
+
+// BucketMapView is a read-only view of a live BucketMap, see View.
+type BucketMapView struct {
+	m         *BucketMap
+	maxPoints int
+}
+
+// GetPointsInRange is BucketMap.GetPointsInRange bounded by the view's
+// point limit. The points gathered before reaching the limit are returned
+// with the error.
+func (v *BucketMapView) GetPointsInRange(key string, start, end uint64) ([]Point, error) {
+	return v.m.getPointsInRange(key, start, end, v.maxPoints)
+}
+
+// Len returns the number of series.
+func (v *BucketMapView) Len() int {
+	return v.m.Len()
+}
@@ -0,0 +1,68 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestBucketMapView(t *testing.T) {
+	const t0 = 1440583200
+	m := NewBucketMap(600)
+	for i := 0; i < 100; i++ {
+		if err := m.AppendPoint("a", t0+60*uint64(i), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := m.View(10)
+
+	points, err := v.GetPointsInRange("a", 0, t0+10000)
+	if err != ErrTooManyPoints || len(points) != 10 || points[9].Value != 9 {
+		t.Fatal(len(points), err)
+	}
+	// exactly the limit is fine, and it applies to each query on its own
+	for i := 0; i < 2; i++ {
+		if points, err := v.GetPointsInRange("a", t0+60*50, t0+60*59); err != nil || len(points) != 10 {
+			t.Fatal(len(points), err)
+		}
+	}
+	if points, err := m.View(0).GetPointsInRange("a", 0, t0+10000); err != nil || len(points) != 100 {
+		t.Fatal("no limit:", len(points), err)
+	}
+	if points, err := v.GetPointsInRange("b", 0, t0+10000); err != nil || points != nil {
+		t.Fatal("missing key:", points, err)
+	}
+
+	// the view follows the live map
+	if err := m.AppendPoint("b", t0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v.Len() != 2 {
+		t.Fatal(v.Len())
+	}
+	if points, err := v.GetPointsInRange("b", 0, t0+10000); err != nil || len(points) != 1 {
+		t.Fatal(len(points), err)
+	}
+}
+
+func TestBucketedLatePoint(t *testing.T) {
+	const t0 = 1440583200
+	b := NewBucketedTimeSeries(600, WithOutOfOrder(OUT_OF_ORDER_SORT, 1))
+	for _, timestamp := range []uint64{t0, t0 + 590, t0 + 1210, t0 + 595, t0 + 1220} {
+		if err := b.AppendPoint(timestamp, float64(timestamp-t0)); err != nil {
+			t.Fatal(timestamp-t0, err)
+		}
+	}
+	// t0+595 belongs to the first window but is stored in the block of the
+	// third, and t0+1220 is still held back
+	points, err := b.GetPointsInRange(t0+591, t0+599)
+	if err != nil || len(points) != 1 || points[0].Value != 595 {
+		t.Fatal(points, err)
+	}
+	points, err = b.GetPointsInRange(t0+600, t0+1199)
+	if err != nil || len(points) != 0 {
+		t.Fatal(points, err)
+	}
+	points, err = b.GetPointsInRange(0, t0+10000)
+	if err != nil || len(points) != 4 {
+		t.Fatal(points, err)
+	}
+}
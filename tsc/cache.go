@@ -0,0 +1,71 @@
+package tsc
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DecodeCache keeps the points of recently decoded closed blocks, so
+// repeated queries over the same window, such as a dashboard refreshing
+// every few seconds, do not decode the same bits again. The least recently
+// used blocks are dropped once the cache holds more than maxPoints points.
+// It is safe for concurrent use and can be shared by many series, see
+// BucketMap.SetDecodeCache.
+type DecodeCache struct {
+	maxPoints int
+	mu        sync.Mutex
+	points    int
+	// most recently used first
+	lru    list.List
+	blocks map[*Series]*list.Element
+}
+
+type cachedBlock struct {
+	s *Series
+	DecodedBlock
+}
+
+// NewDecodeCache returns an empty DecodeCache holding up to maxPoints
+// points.
+func NewDecodeCache(maxPoints int) *DecodeCache {
+	return &DecodeCache{maxPoints: maxPoints, blocks: map[*Series]*list.Element{}}
+}
+
+// Len returns the number of blocks held.
+func (c *DecodeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.blocks)
+}
+
+// decode returns the points of s, which must no longer be appended to,
+// from the cache or by decoding it. Blocks larger than the cache are
+// decoded but not kept.
+func (c *DecodeCache) decode(s *Series) (DecodedBlock, error) {
+	c.mu.Lock()
+	if e, ok := c.blocks[s]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cachedBlock).DecodedBlock, nil
+	}
+	c.mu.Unlock()
+
+	// decoded without the lock, another query may decode it too
+	b, err := s.DecodeBlock()
+	if err != nil || b.Len() > c.maxPoints {
+		return b, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.blocks[s]; !ok {
+		c.blocks[s] = c.lru.PushFront(&cachedBlock{s, b})
+		c.points += b.Len()
+	}
+	for c.points > c.maxPoints {
+		oldest := c.lru.Remove(c.lru.Back()).(*cachedBlock)
+		delete(c.blocks, oldest.s)
+		c.points -= oldest.Len()
+	}
+	return b, nil
+}
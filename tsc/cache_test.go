@@ -0,0 +1,76 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestDecodeCacheEviction(t *testing.T) {
+	const t0 = 1440583200 - 1440583200%600
+	b := NewBucketedTimeSeries(600)
+	for i := 0; i < 40; i++ {
+		if err := b.AppendPoint(t0+60*uint64(i), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// three closed blocks of ten points and the current one
+	c := NewDecodeCache(25)
+	b.SetDecodeCache(c)
+	query := func(block int) {
+		start := t0 + 600*uint64(block)
+		points, err := b.GetPointsInRange(start, start+599)
+		if err != nil || len(points) != 10 || points[0].Value != float64(10*block) {
+			t.Fatalf("block %d: %v %v", block, points, err)
+		}
+	}
+	cached := func(block int) bool {
+		_, ok := c.blocks[b.blocks[block].s]
+		return ok
+	}
+
+	query(0)
+	query(1)
+	query(3)
+	if c.Len() != 2 || !cached(0) || !cached(1) {
+		t.Fatal("the current block is cached or a closed one is not:", c.Len())
+	}
+	query(0)
+	query(2)
+	if c.Len() != 2 || !cached(0) || cached(1) || !cached(2) {
+		t.Fatal("block 1 should be evicted as the least recently used")
+	}
+	if c.points != 20 {
+		t.Fatal(c.points)
+	}
+
+	// a block larger than the whole cache is decoded but not kept
+	small := NewDecodeCache(5)
+	b.SetDecodeCache(small)
+	query(0)
+	if small.Len() != 0 {
+		t.Fatal(small.Len())
+	}
+}
+
+func TestDecodeCacheRange(t *testing.T) {
+	const t0 = 1440583200 - 1440583200%600
+	b := NewBucketedTimeSeries(600)
+	for i := 0; i < 40; i++ {
+		b.AppendPoint(t0+60*uint64(i), float64(i))
+	}
+	want, err := b.GetPointsInRange(t0+90, t0+1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetDecodeCache(NewDecodeCache(100))
+	for i := 0; i < 2; i++ {
+		got, err := b.GetPointsInRange(t0+90, t0+1500)
+		if err != nil || len(got) != len(want) {
+			t.Fatal(len(got), len(want), err)
+		}
+		for j := range want {
+			if got[j] != want[j] {
+				t.Fatalf("point %d: %v, want %v", j, got[j], want[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+const CHECKSUM_BITS = 8
+
+var errChecksum = errors.New("Point checksum mismatch")
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WithChecksum stores a CHECKSUM_BITS checksum of every point after it, so
+// a corrupt stream fails at the first point that decodes wrongly instead of
+// returning plausible values. It costs CHECKSUM_BITS per point and disables
+// the fast path for runs of unchanged points. Streams written with it must
+// be read with it.
+func WithChecksum() Option {
+	return func(s *Series) {
+		s.checksum = true
+	}
+}
+
+// pointChecksum returns the checksum of a point, value as IEEE 754 bits.
+func pointChecksum(timestamp, value uint64) uint64 {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:], timestamp)
+	binary.BigEndian.PutUint64(buf[8:], value)
+	return uint64(crc32.Checksum(buf[:], checksumTable)) & (1<<CHECKSUM_BITS - 1)
+}
+
+func (s *Series) verifyChecksum(timestamp, value uint64) error {
+	sum, err := s.Bs.ReadValueFromBitStream(CHECKSUM_BITS)
+	if err != nil {
+		return err
+	}
+	if sum != pointChecksum(timestamp, value) {
+		return errChecksum
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"github.com/huangaz/tsc/bitUtil"
+	"slices"
+)
+
+const (
+	CHIMP_THRESHOLD         = 6
+	CHIMP_LEADING_CODE_BITS = 3
+	CHIMP_CENTER_BITS       = 6
+	// never a rounded leading zeros count, so the next value stores its own
+	CHIMP_NO_LEADING         = 64 + 1
+	CHIMP128_PREVIOUS_VALUES = 128
+	CHIMP128_INDEX_BITS      = 7
+	CHIMP128_THRESHOLD       = CHIMP_THRESHOLD + CHIMP128_INDEX_BITS
+	CHIMP128_KEY_BITS        = CHIMP128_THRESHOLD + 1
+)
+
+/*
+* Chimp, Liakos et al. 2022: leading zeros are rounded down to one of eight
+* values so they fit in 3 bits, and only xors with many trailing zeros store
+* a center block.
+*
+* tag	meaning
+* 00	same value as the previous one
+* 01	3 bits leading code, 6 bits center size, center bits
+* 10	64 - previous leading bits
+* 11	3 bits leading code, 64 - leading bits
+ */
+var chimpLeadingValues = [8]uint64{0, 8, 12, 16, 18, 20, 22, 24}
+
+// chimpLeading rounds the leading zeros of xor down to a representable
+// value and returns it with its code.
+func chimpLeading(xor uint64) (uint64, uint64) {
+	leading := bitUtil.Clz(xor)
+	code := uint64(len(chimpLeadingValues) - 1)
+	for chimpLeadingValues[code] > leading {
+		code--
+	}
+	return chimpLeadingValues[code], code
+}
+
+// chimpCodec is Chimp. Both directions start with a zero previous value
+// and leading zeros, so the first value needs no special case.
+type chimpCodec struct {
+	prev    uint64
+	leading uint64
+}
+
+func (c *chimpCodec) ID() uint8 {
+	return VALUE_CODEC_CHIMP
+}
+
+func (c *chimpCodec) Append(bs *bitUtil.BitStream, value uint64) {
+	xor := value ^ c.prev
+	c.prev = value
+	if xor == 0 {
+		bs.AddValueToBitStream(0, 2)
+		c.leading = CHIMP_NO_LEADING
+		return
+	}
+
+	leading, code := chimpLeading(xor)
+	trailing := bitUtil.Ctz(xor)
+	if trailing > CHIMP_THRESHOLD {
+		center := 64 - leading - trailing
+		bs.AddValueToBitStream(1, 2)
+		bs.AddValueToBitStream(code, CHIMP_LEADING_CODE_BITS)
+		bs.AddValueToBitStream(center, CHIMP_CENTER_BITS)
+		bs.AddValueToBitStream(xor>>trailing, center)
+		c.leading = CHIMP_NO_LEADING
+	} else if leading == c.leading {
+		bs.AddValueToBitStream(2, 2)
+		bs.AddValueToBitStream(xor, 64-leading)
+	} else {
+		bs.AddValueToBitStream(3, 2)
+		bs.AddValueToBitStream(code, CHIMP_LEADING_CODE_BITS)
+		bs.AddValueToBitStream(xor, 64-leading)
+		c.leading = leading
+	}
+}
+
+func (c *chimpCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	tag, err := bs.ReadValueFromBitStream(2)
+	if err != nil {
+		return 0, err
+	}
+	xor, err := readChimpTagged(bs, tag, &c.leading)
+	if err != nil {
+		return 0, err
+	}
+	c.prev ^= xor
+	return c.prev, nil
+}
+
+// readChimpTagged reads the xor with the previous value that follows tag,
+// keeping the stored leading zeros in step with the encoder.
+func readChimpTagged(bs *bitUtil.BitStream, tag uint64, leading *uint64) (uint64, error) {
+	switch tag {
+	case 0:
+		*leading = CHIMP_NO_LEADING
+		return 0, nil
+	case 1:
+		code, err := bs.ReadValueFromBitStream(CHIMP_LEADING_CODE_BITS)
+		if err != nil {
+			return 0, err
+		}
+		center, err := bs.ReadValueFromBitStream(CHIMP_CENTER_BITS)
+		if err != nil {
+			return 0, err
+		}
+		return readChimpCenter(bs, chimpLeadingValues[code], center, leading)
+	case 3:
+		code, err := bs.ReadValueFromBitStream(CHIMP_LEADING_CODE_BITS)
+		if err != nil {
+			return 0, err
+		}
+		*leading = chimpLeadingValues[code]
+	}
+	if *leading > 64 {
+		return 0, errXorBlock
+	}
+	return bs.ReadValueFromBitStream(64 - *leading)
+}
+
+func readChimpCenter(bs *bitUtil.BitStream, leading, center uint64, stored *uint64) (uint64, error) {
+	if center == 0 || leading+center > 64 {
+		return 0, errXorBlock
+	}
+	xor, err := bs.ReadValueFromBitStream(center)
+	if err != nil {
+		return 0, err
+	}
+	*stored = CHIMP_NO_LEADING
+	return xor << (64 - leading - center), nil
+}
+
+func (c *chimpCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 16)
+	data = binary.BigEndian.AppendUint64(data, c.prev)
+	data = binary.BigEndian.AppendUint64(data, c.leading)
+	return data, nil
+}
+
+func (c *chimpCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return errCodecState
+	}
+	c.prev = binary.BigEndian.Uint64(data)
+	c.leading = binary.BigEndian.Uint64(data[8:])
+	return nil
+}
+
+func (c *chimpCodec) clone() ValueCodec {
+	res := *c
+	return &res
+}
+
+/*
+* Chimp128 xors each value with whichever of the previous 128 shares the most
+* trailing bits, found through a table keyed by the low bits of the value.
+* Tags 00 and 01 are followed by the 7 bit position of that value, the other
+* tags always refer to the previous value.
+ */
+type chimp128Codec struct {
+	values  [CHIMP128_PREVIOUS_VALUES]uint64
+	index   uint64
+	leading uint64
+	// position of the last value with each key, appending only; 0 is the
+	// zero value both directions start from
+	indices []uint32
+}
+
+func (c *chimp128Codec) ID() uint8 {
+	return VALUE_CODEC_CHIMP128
+}
+
+func (c *chimp128Codec) Append(bs *bitUtil.BitStream, value uint64) {
+	if c.indices == nil {
+		c.indices = make([]uint32, 1<<CHIMP128_KEY_BITS)
+	}
+	key := value & (1<<CHIMP128_KEY_BITS - 1)
+	prevIndex := c.index % CHIMP128_PREVIOUS_VALUES
+	xor := value ^ c.values[prevIndex]
+	// Positions wrap around with 32 bits; a stale match only costs space
+	// since the position is stored.
+	if candidate := c.indices[key]; uint32(c.index)-candidate < CHIMP128_PREVIOUS_VALUES {
+		i := uint64(candidate) % CHIMP128_PREVIOUS_VALUES
+		if x := value ^ c.values[i]; x == 0 || bitUtil.Ctz(x) > CHIMP128_THRESHOLD {
+			prevIndex, xor = i, x
+		}
+	}
+
+	if xor == 0 {
+		bs.AddValueToBitStream(0, 2)
+		bs.AddValueToBitStream(prevIndex, CHIMP128_INDEX_BITS)
+		c.leading = CHIMP_NO_LEADING
+	} else {
+		leading, code := chimpLeading(xor)
+		trailing := bitUtil.Ctz(xor)
+		if trailing > CHIMP128_THRESHOLD {
+			center := 64 - leading - trailing
+			bs.AddValueToBitStream(1, 2)
+			bs.AddValueToBitStream(prevIndex, CHIMP128_INDEX_BITS)
+			bs.AddValueToBitStream(code, CHIMP_LEADING_CODE_BITS)
+			bs.AddValueToBitStream(center, CHIMP_CENTER_BITS)
+			bs.AddValueToBitStream(xor>>trailing, center)
+			c.leading = CHIMP_NO_LEADING
+		} else if leading == c.leading {
+			bs.AddValueToBitStream(2, 2)
+			bs.AddValueToBitStream(xor, 64-leading)
+		} else {
+			bs.AddValueToBitStream(3, 2)
+			bs.AddValueToBitStream(code, CHIMP_LEADING_CODE_BITS)
+			bs.AddValueToBitStream(xor, 64-leading)
+			c.leading = leading
+		}
+	}
+	c.push(value)
+	c.indices[key] = uint32(c.index)
+}
+
+func (c *chimp128Codec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	tag, err := bs.ReadValueFromBitStream(2)
+	if err != nil {
+		return 0, err
+	}
+	ref := c.values[c.index%CHIMP128_PREVIOUS_VALUES]
+	var xor uint64
+	switch tag {
+	case 0, 1:
+		i, err := bs.ReadValueFromBitStream(CHIMP128_INDEX_BITS)
+		if err != nil {
+			return 0, err
+		}
+		ref = c.values[i]
+		if tag == 0 {
+			c.leading = CHIMP_NO_LEADING
+			break
+		}
+		code, err := bs.ReadValueFromBitStream(CHIMP_LEADING_CODE_BITS)
+		if err != nil {
+			return 0, err
+		}
+		center, err := bs.ReadValueFromBitStream(CHIMP_CENTER_BITS)
+		if err != nil {
+			return 0, err
+		}
+		xor, err = readChimpCenter(bs, chimpLeadingValues[code], center, &c.leading)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		xor, err = readChimpTagged(bs, tag, &c.leading)
+		if err != nil {
+			return 0, err
+		}
+	}
+	value := ref ^ xor
+	c.push(value)
+	return value, nil
+}
+
+// push makes value the previous one. Slot 0 holds the zero value both
+// directions start from.
+func (c *chimp128Codec) push(value uint64) {
+	c.index++
+	c.values[c.index%CHIMP128_PREVIOUS_VALUES] = value
+}
+
+func (c *chimp128Codec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 8*(CHIMP128_PREVIOUS_VALUES+2)+4*len(c.indices))
+	for _, v := range c.values {
+		data = binary.BigEndian.AppendUint64(data, v)
+	}
+	data = binary.BigEndian.AppendUint64(data, c.index)
+	data = binary.BigEndian.AppendUint64(data, c.leading)
+	for _, i := range c.indices {
+		data = binary.BigEndian.AppendUint32(data, i)
+	}
+	return data, nil
+}
+
+func (c *chimp128Codec) UnmarshalBinary(data []byte) error {
+	size := 8 * (CHIMP128_PREVIOUS_VALUES + 2)
+	if len(data) != size && len(data) != size+4<<CHIMP128_KEY_BITS {
+		return errCodecState
+	}
+	for i := range c.values {
+		c.values[i] = binary.BigEndian.Uint64(data[8*i:])
+	}
+	c.index = binary.BigEndian.Uint64(data[size-16:])
+	c.leading = binary.BigEndian.Uint64(data[size-8:])
+	c.indices = nil
+	if len(data) > size {
+		c.indices = make([]uint32, 1<<CHIMP128_KEY_BITS)
+		for i := range c.indices {
+			c.indices[i] = binary.BigEndian.Uint32(data[size+4*i:])
+		}
+	}
+	return nil
+}
+
+func (c *chimp128Codec) clone() ValueCodec {
+	res := *c
+	res.indices = slices.Clone(c.indices)
+	return &res
+}
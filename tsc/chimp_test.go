@@ -0,0 +1,84 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+// chimpValues repeats a pattern with a period of 100 points, so Chimp128
+// finds most values in its window, with special values and long xors mixed
+// in.
+func chimpValues(n int) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		v := 20 + math.Round(10*math.Sin(float64(i%100)/7)*100)/100
+		switch i % 37 {
+		case 5:
+			v = math.NaN()
+		case 11:
+			v = math.Copysign(0, -1)
+		case 17:
+			v = math.Pi * float64(i)
+		case 23:
+			v = math.MaxFloat64
+		}
+		points[i] = Point{1440583200 + 60*uint64(i), v}
+	}
+	return points
+}
+
+func TestChimpRoundTrip(t *testing.T) {
+	for _, id := range []uint8{VALUE_CODEC_CHIMP, VALUE_CODEC_CHIMP128} {
+		points := chimpValues(1000)
+		if err := RoundTrip(points, WithValueCodec(id)); err != nil {
+			t.Fatalf("codec %d: %v", id, err)
+		}
+		if err := RoundTrip(points[:1], WithValueCodec(id)); err != nil {
+			t.Fatalf("codec %d, one point: %v", id, err)
+		}
+	}
+}
+
+func TestChimpSize(t *testing.T) {
+	size := map[uint8]uint64{}
+	for _, id := range []uint8{VALUE_CODEC_XOR, VALUE_CODEC_CHIMP, VALUE_CODEC_CHIMP128} {
+		s := NewSeries(WithValueCodec(id))
+		for _, p := range chimpValues(5000) {
+			s.Append(p.Timestamp, p.Value)
+		}
+		size[id] = s.Bs.NumBits
+	}
+	if size[VALUE_CODEC_CHIMP] >= size[VALUE_CODEC_XOR] || size[VALUE_CODEC_CHIMP128] >= size[VALUE_CODEC_CHIMP]/2 {
+		t.Fatalf("bits: xor %d, chimp %d, chimp128 %d",
+			size[VALUE_CODEC_XOR], size[VALUE_CODEC_CHIMP], size[VALUE_CODEC_CHIMP128])
+	}
+}
+
+// TestChimp128Snapshot checks that the window and index are copied: the
+// snapshot and the original are written on after it and both must decode.
+func TestChimp128Snapshot(t *testing.T) {
+	points := chimpValues(600)
+	s := NewSeries(WithValueCodec(VALUE_CODEC_CHIMP128))
+	for _, p := range points[:300] {
+		s.Append(p.Timestamp, p.Value)
+	}
+	snap := s.Snapshot()
+	for _, p := range points[300:] {
+		s.Append(p.Timestamp, p.Value)
+		snap.Append(p.Timestamp, p.Value+1)
+	}
+	for i, p := range points {
+		_, value, err := s.Read()
+		if err != nil || math.Float64bits(value) != math.Float64bits(p.Value) {
+			t.Fatalf("original, point %d: %v %v, want %v", i, value, err, p.Value)
+		}
+		want := p.Value
+		if i >= 300 {
+			want++
+		}
+		_, value, err = snap.Read()
+		if err != nil || math.Float64bits(value) != math.Float64bits(want) {
+			t.Fatalf("snapshot, point %d: %v %v, want %v", i, value, err, want)
+		}
+	}
+}
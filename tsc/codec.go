@@ -0,0 +1,78 @@
+package tsc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var errCodecState = errors.New("Invalid codec state")
+
+// codecState is what a registry needs from ValueCodec and TimeCodec.
+type codecState interface {
+	ID() uint8
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+// cloner is implemented by codecs that copy their own state, which clone
+// prefers to a round trip through MarshalBinary.
+type cloner[T any] interface {
+	clone() T
+}
+
+// registry maps codec IDs to constructors.
+type registry[T codecState] struct {
+	kind   string
+	mu     sync.RWMutex
+	codecs map[uint8]func() T
+}
+
+func newRegistry[T codecState](kind string) *registry[T] {
+	return &registry[T]{kind: kind, codecs: map[uint8]func() T{}}
+}
+
+func (r *registry[T]) register(id uint8, newCodec func() T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.codecs[id]; ok {
+		panic(fmt.Sprintf("tsc: %s codec %d registered twice", r.kind, id))
+	}
+	r.codecs[id] = newCodec
+}
+
+// lookup returns a new codec registered under id, if there is one.
+func (r *registry[T]) lookup(id uint8) (T, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	newCodec, ok := r.codecs[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return newCodec(), true
+}
+
+func (r *registry[T]) new(id uint8) T {
+	c, ok := r.lookup(id)
+	if !ok {
+		panic(fmt.Sprintf("tsc: unknown %s codec %d", r.kind, id))
+	}
+	return c
+}
+
+// clone returns a new codec of the same kind holding the state of c.
+func (r *registry[T]) clone(c T) T {
+	if c, ok := any(c).(cloner[T]); ok {
+		return c.clone()
+	}
+	res := r.new(c.ID())
+	state, err := c.MarshalBinary()
+	if err == nil {
+		err = res.UnmarshalBinary(state)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("tsc: cloning %s codec %d: %v", r.kind, c.ID(), err))
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package tsc
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// TestCodecClone checks that every built-in codec clones itself into the
+// same state a MarshalBinary round trip gives, without sharing it.
+func TestCodecClone(t *testing.T) {
+	points := chimpValues(300)
+	for _, id := range []uint8{VALUE_CODEC_XOR, VALUE_CODEC_DECIMAL, VALUE_CODEC_CHIMP, VALUE_CODEC_CHIMP128, VALUE_CODEC_PROMETHEUS} {
+		c := valueCodecs.new(id)
+		if _, ok := c.(cloner[ValueCodec]); !ok {
+			t.Fatalf("value codec %d does not clone itself", id)
+		}
+		var s Series
+		for _, p := range points {
+			c.Append(&s.Bs, math.Float64bits(p.Value))
+		}
+		testClone(t, valueCodecs, c, func() { c.Append(&s.Bs, 1) })
+	}
+	for _, id := range []uint8{TIME_CODEC_DOD, TIME_CODEC_FIXED_STEP, TIME_CODEC_DOD64, TIME_CODEC_SPARSE, TIME_CODEC_AUTO, TIME_CODEC_PROMETHEUS, TIME_CODEC_DOD_ESCAPE} {
+		c := timeCodecs.new(id)
+		if _, ok := c.(cloner[TimeCodec]); !ok {
+			t.Fatalf("time codec %d does not clone itself", id)
+		}
+		var s Series
+		for _, p := range points {
+			c.Append(&s.Bs, p.Timestamp)
+		}
+		last := points[len(points)-1].Timestamp
+		testClone(t, timeCodecs, c, func() { c.Append(&s.Bs, last+7) })
+	}
+}
+
+// testClone clones c, checks the clone holds its state, then checks that
+// appending through c does not change the clone.
+func testClone[T codecState](t *testing.T, r *registry[T], c T, appendMore func()) {
+	t.Helper()
+	want, _ := c.MarshalBinary()
+	res := r.clone(c)
+	if state, _ := res.MarshalBinary(); !bytes.Equal(state, want) {
+		t.Fatalf("%s codec %d: clone has another state", r.kind, c.ID())
+	}
+	appendMore()
+	if state, _ := res.MarshalBinary(); !bytes.Equal(state, want) {
+		t.Fatalf("%s codec %d: clone follows the original", r.kind, c.ID())
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	s := NewSeries(WithValueCodec(VALUE_CODEC_CHIMP128))
+	for _, p := range chimpValues(benchmarkPoints) {
+		s.Append(p.Timestamp, p.Value)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		s.Snapshot()
+	}
+}
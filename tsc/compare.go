@@ -0,0 +1,57 @@
+package tsc
+
+import (
+	"time"
+)
+
+// Codec names a Series configuration to evaluate with Compare.
+type Codec struct {
+	Name    string
+	Options []Option
+}
+
+// Report is the result of encoding a dataset with one Codec.
+type Report struct {
+	Name         string
+	Points       int
+	Bytes        int
+	BitsPerPoint float64
+	EncodeTime   time.Duration
+	DecodeTime   time.Duration
+	// Err is set if the points did not decode to what was appended, in
+	// which case the other figures should not be trusted.
+	Err error
+}
+
+// Compare encodes points with each codec, decodes them again and reports
+// the encoded size and the time spent in both directions, in the order the
+// codecs were given. Times are measured once over the whole dataset, so
+// use enough points for them to be meaningful.
+func Compare(points []Point, codecs ...Codec) []Report {
+	reports := make([]Report, 0, len(codecs))
+	for _, c := range codecs {
+		r := Report{Name: c.Name, Points: len(points)}
+		s := NewSeries(c.Options...)
+		start := time.Now()
+		for _, p := range points {
+			s.Append(p.Timestamp, p.Value)
+		}
+		r.EncodeTime = time.Since(start)
+		r.Bytes = s.Bs.Len()
+		if len(points) > 0 {
+			r.BitsPerPoint = float64(s.Bs.NumBits) / float64(len(points))
+		}
+
+		timestamps := make([]uint64, 0, len(points))
+		values := make([]float64, 0, len(points))
+		start = time.Now()
+		_, _, r.Err = s.ReadAppend(timestamps, values)
+		r.DecodeTime = time.Since(start)
+		if r.Err == nil {
+			s.resetRead()
+			r.Err = s.verify(points)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
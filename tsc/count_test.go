@@ -0,0 +1,58 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	const t0 = 1440583200
+	tests := []struct {
+		name string
+		s    *Series
+		// points stored after appending t0, t0+60, t0+120, t0+180 and
+		// t0+90, and after Flush
+		count, flushed uint64
+		last           uint64
+	}{
+		{"plain", NewSeries(), 4, 4, t0 + 180},
+		{"constant runs", NewSeries(WithConstantRuns(2)), 4, 4, t0 + 180},
+		{"sort window", NewSeries(WithOutOfOrder(OUT_OF_ORDER_SORT, 3)), 2, 5, t0 + 180},
+	}
+	for _, test := range tests {
+		s := test.s
+		if _, ok := s.FirstTimestamp(); ok || s.Count() != 0 {
+			t.Fatalf("%s: empty series has points", test.name)
+		}
+		if _, ok := s.LastTimestamp(); ok {
+			t.Fatalf("%s: empty series has a last timestamp", test.name)
+		}
+		for _, timestamp := range []uint64{t0, t0 + 60, t0 + 120, t0 + 180, t0 + 90} {
+			s.AppendChecked(timestamp, 1)
+		}
+		if s.Count() != test.count {
+			t.Fatalf("%s: %d points before Flush, want %d", test.name, s.Count(), test.count)
+		}
+		s.Flush()
+		first, ok := s.FirstTimestamp()
+		last, _ := s.LastTimestamp()
+		if s.Count() != test.flushed || !ok || first != t0 || last != test.last {
+			t.Fatalf("%s: %d points from %d to %d", test.name, s.Count(), first, last)
+		}
+	}
+}
+
+func TestCountReportByException(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries()
+	s.SetReportByException(1, 3600)
+	for i := 0; i < 10; i++ {
+		s.Append(t0+60*uint64(i), 5)
+	}
+	if last, _ := s.LastTimestamp(); s.Count() != 1 || last != t0 {
+		t.Fatal(s.Count(), last)
+	}
+	s.Flush()
+	if last, _ := s.LastTimestamp(); s.Count() != 2 || last != t0+540 {
+		t.Fatal(s.Count(), last)
+	}
+}
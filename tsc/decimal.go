@@ -0,0 +1,185 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/huangaz/tsc/bitUtil"
+	"math"
+)
+
+const (
+	MAX_DECIMAL_SCALE      = 15
+	DECIMAL_SCALE_BITS     = 4
+	DECIMAL_CONTROL_BITS   = 4
+	MAX_EXACT_FLOAT64_BITS = 53
+)
+
+/*
+* Decimal values, enabled by WithDecimalValues().
+*
+* tag	meaning
+* 0	value*10^scale is an integer n, followed by n - prev n
+* 10	xor encoded value, see appendValue()
+* 11	scale grows to the following 4 bits, followed by n - prev n
+*
+* n - prev n is zigzag encoded:
+* tag	value bits
+* 0	- (no change)
+* 10	8
+* 110	16
+* 1110	32
+* 1111	64
+ */
+var decimalDeltaBits = []uint64{0, 8, 16, 32, 64}
+
+var errDecimalScale = errors.New("Invalid decimal scale")
+
+var powersOfTen [MAX_DECIMAL_SCALE + 1]float64
+
+func init() {
+	p := 1.0
+	for i := range powersOfTen {
+		powersOfTen[i] = p
+		p *= 10
+	}
+}
+
+// WithDecimalValues stores values that are exact decimals, like prices, as
+// scaled integers with delta coding. The scale is the number of decimal
+// places of the most precise value seen so far; values with more than
+// MAX_DECIMAL_SCALE places fall back to the xor encoding.
+func WithDecimalValues() Option {
+	return WithValueCodec(VALUE_CODEC_DECIMAL)
+}
+
+type decimalCodec struct {
+	// fallback, also keeps the previous value for it
+	xor         xorCodec
+	prevDecimal int64
+	scale       uint64
+}
+
+func (c *decimalCodec) ID() uint8 {
+	return VALUE_CODEC_DECIMAL
+}
+
+// toDecimal returns n such that n/10^scale is exactly value.
+func toDecimal(value float64, scale uint64) (int64, bool) {
+	n := math.Round(value * powersOfTen[scale])
+	if math.Abs(n) >= 1<<MAX_EXACT_FLOAT64_BITS {
+		return 0, false
+	}
+	// divide the integer the decoder will see, which also rules out -0
+	back := float64(int64(n)) / powersOfTen[scale]
+	return int64(n), math.Float64bits(back) == math.Float64bits(value)
+}
+
+func (c *decimalCodec) Append(bs *bitUtil.BitStream, value uint64) {
+	v := math.Float64frombits(value)
+	for scale := c.scale; scale <= MAX_DECIMAL_SCALE; scale++ {
+		n, ok := toDecimal(v, scale)
+		if !ok {
+			continue
+		}
+		if scale == c.scale {
+			bs.AddValueToBitStream(0, 1)
+		} else {
+			bs.AddValueToBitStream(3, 2)
+			bs.AddValueToBitStream(scale, DECIMAL_SCALE_BITS)
+			c.prevDecimal *= int64(powersOfTen[scale-c.scale])
+			c.scale = scale
+		}
+		c.appendDelta(bs, n-c.prevDecimal)
+		c.prevDecimal = n
+		// keep the xor state in step for the next fallback
+		c.xor.prev = value
+		return
+	}
+	bs.AddValueToBitStream(2, 2)
+	c.xor.Append(bs, value)
+}
+
+func (c *decimalCodec) appendDelta(bs *bitUtil.BitStream, delta int64) {
+	zigzag := uint64(delta<<1) ^ uint64(delta>>63)
+	for i, bits := range decimalDeltaBits {
+		if i == len(decimalDeltaBits)-1 || zigzag < 1<<bits {
+			if i < DECIMAL_CONTROL_BITS {
+				// i ones and a terminating zero
+				bs.AddValueToBitStream((1<<uint(i+1))-2, uint64(i+1))
+			} else {
+				bs.AddValueToBitStream((1<<DECIMAL_CONTROL_BITS)-1, DECIMAL_CONTROL_BITS)
+			}
+			if bits > 0 {
+				bs.AddValueToBitStream(zigzag, bits)
+			}
+			return
+		}
+	}
+}
+
+func (c *decimalCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	control, err := bs.ReadValueFromBitStream(1)
+	if err != nil {
+		return 0, err
+	}
+	if control == 1 {
+		rescale, err := bs.ReadValueFromBitStream(1)
+		if err != nil {
+			return 0, err
+		}
+		if rescale == 0 {
+			return c.xor.Read(bs)
+		}
+		scale, err := bs.ReadValueFromBitStream(DECIMAL_SCALE_BITS)
+		if err != nil {
+			return 0, err
+		}
+		if scale <= c.scale || scale > MAX_DECIMAL_SCALE {
+			return 0, errDecimalScale
+		}
+		c.prevDecimal *= int64(powersOfTen[scale-c.scale])
+		c.scale = scale
+	}
+
+	index, err := bs.ReadUnary(DECIMAL_CONTROL_BITS)
+	if err != nil {
+		return 0, err
+	}
+	var zigzag uint64
+	if bits := decimalDeltaBits[index]; bits > 0 {
+		if zigzag, err = bs.ReadValueFromBitStream(bits); err != nil {
+			return 0, err
+		}
+	}
+	c.prevDecimal += int64(zigzag>>1) ^ -int64(zigzag&1)
+	value := math.Float64bits(float64(c.prevDecimal) / powersOfTen[c.scale])
+	c.xor.prev = value
+	return value, nil
+}
+
+func (c *decimalCodec) MarshalBinary() ([]byte, error) {
+	data, _ := c.xor.MarshalBinary()
+	data = binary.BigEndian.AppendUint64(data, uint64(c.prevDecimal))
+	data = binary.BigEndian.AppendUint64(data, c.scale)
+	return data, nil
+}
+
+func (c *decimalCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 40 {
+		return errCodecState
+	}
+	if err := c.xor.UnmarshalBinary(data[:24]); err != nil {
+		return err
+	}
+	c.prevDecimal = int64(binary.BigEndian.Uint64(data[24:]))
+	c.scale = binary.BigEndian.Uint64(data[32:])
+	if c.scale > MAX_DECIMAL_SCALE {
+		return errCodecState
+	}
+	return nil
+}
+
+func (c *decimalCodec) clone() ValueCodec {
+	res := *c
+	return &res
+}
@@ -0,0 +1,35 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecimalValues(t *testing.T) {
+	const t0 = 1440583200
+	// the scale grows from 0 to 2 and then 4, pi and the special values
+	// fall back to the xor encoding, large jumps need 32 and 64 bit deltas
+	values := []float64{100, 101, 101, 100.5, 100.25, 99.99, 99.9999, math.Pi,
+		99.9998, math.NaN(), math.Copysign(0, -1), math.Inf(1), 1e12, -1e12, 0.0001}
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{t0 + 60*uint64(i), v}
+	}
+	if err := RoundTrip(points, WithDecimalValues()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecimalValuesSize(t *testing.T) {
+	decimal, xor := NewSeries(WithDecimalValues()), NewSeries()
+	price := 1234.56
+	for i := 0; i < 1000; i++ {
+		price += float64(i%7-3) / 100
+		price = math.Round(price*100) / 100
+		decimal.Append(1440583200+60*uint64(i), price)
+		xor.Append(1440583200+60*uint64(i), price)
+	}
+	if decimal.Bs.NumBits >= xor.Bs.NumBits/2 {
+		t.Fatalf("decimal encoding uses %d bits, xor %d", decimal.Bs.NumBits, xor.Bs.NumBits)
+	}
+}
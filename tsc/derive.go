@@ -0,0 +1,224 @@
+package tsc
+
+import (
+	"math"
+)
+
+// rangeReader reads the points of a series with timestamps in [from, to].
+type rangeReader struct {
+	s        *Series
+	from, to uint64
+	err      error
+}
+
+func (r *rangeReader) next() (uint64, float64, bool) {
+	for r.err == nil && !r.s.done() {
+		timestamp, value, err := r.s.Read()
+		if err != nil {
+			r.err = err
+			return 0, 0, false
+		}
+		if timestamp > r.to {
+			return 0, 0, false
+		}
+		if timestamp >= r.from {
+			return timestamp, value, true
+		}
+	}
+	return 0, 0, false
+}
+
+// secondsPerUnit returns the length in seconds of a unit of the timestamps
+// of s, see WithResolution.
+func (s *Series) secondsPerUnit() float64 {
+	if s.resolution == 0 {
+		return 1
+	}
+	return s.resolution.Seconds()
+}
+
+// Derivative iterates over the per-second rate of change between
+// consecutive points of a series in [from, to]. Each rate is reported at
+// the timestamp of the later point; points sharing a timestamp with their
+// predecessor are skipped. It reads from a Snapshot taken by NewDerivative
+// and leaves the read position of the series alone.
+type Derivative struct {
+	r         rangeReader
+	seconds   float64
+	started   bool
+	prevTime  uint64
+	prevValue float64
+	timestamp uint64
+	rate      float64
+}
+
+func NewDerivative(s *Series, from, to uint64) *Derivative {
+	return &Derivative{r: rangeReader{s: s.Snapshot(), from: from, to: to}, seconds: s.secondsPerUnit()}
+}
+
+func (d *Derivative) Next() bool {
+	for {
+		timestamp, value, ok := d.r.next()
+		if !ok {
+			return false
+		}
+		if !d.started || timestamp == d.prevTime {
+			d.started = true
+			d.prevTime, d.prevValue = timestamp, value
+			continue
+		}
+		d.timestamp = timestamp
+		d.rate = (value - d.prevValue) / (float64(timestamp-d.prevTime) * d.seconds)
+		d.prevTime, d.prevValue = timestamp, value
+		return true
+	}
+}
+
+func (d *Derivative) At() (uint64, float64) {
+	return d.timestamp, d.rate
+}
+
+func (d *Derivative) Err() error {
+	return d.r.err
+}
+
+// Integral iterates over the running trapezoidal integral (value-seconds)
+// of a series in [from, to], starting at 0 on the first point. Like
+// Derivative, it reads from a Snapshot.
+type Integral struct {
+	r         rangeReader
+	seconds   float64
+	started   bool
+	prevTime  uint64
+	prevValue float64
+	sum       float64
+}
+
+func NewIntegral(s *Series, from, to uint64) *Integral {
+	return &Integral{r: rangeReader{s: s.Snapshot(), from: from, to: to}, seconds: s.secondsPerUnit()}
+}
+
+func (i *Integral) Next() bool {
+	timestamp, value, ok := i.r.next()
+	if !ok {
+		return false
+	}
+	if i.started {
+		i.sum += (value + i.prevValue) / 2 * float64(timestamp-i.prevTime) * i.seconds
+	}
+	i.started = true
+	i.prevTime, i.prevValue = timestamp, value
+	return true
+}
+
+func (i *Integral) At() (uint64, float64) {
+	return i.prevTime, i.sum
+}
+
+func (i *Integral) Err() error {
+	return i.r.err
+}
+
+// Smoothed iterates over a series in [from, to] smoothed with Holt's double
+// exponential smoothing. alpha weights the newest value against the level
+// and beta the newest slope against the trend; with beta 0 the trend stays
+// 0 and this is a plain exponentially weighted moving average. Like
+// Derivative, it reads from a Snapshot.
+type Smoothed struct {
+	r           rangeReader
+	alpha, beta float64
+	started     bool
+	timestamp   uint64
+	level       float64
+	trend       float64
+}
+
+func NewEWMA(s *Series, from, to uint64, alpha float64) *Smoothed {
+	return NewHolt(s, from, to, alpha, 0)
+}
+
+func NewHolt(s *Series, from, to uint64, alpha, beta float64) *Smoothed {
+	return &Smoothed{r: rangeReader{s: s.Snapshot(), from: from, to: to}, alpha: alpha, beta: beta}
+}
+
+func (m *Smoothed) Next() bool {
+	timestamp, value, ok := m.r.next()
+	if !ok {
+		return false
+	}
+	m.timestamp = timestamp
+	if !m.started {
+		m.started = true
+		m.level = value
+		return true
+	}
+	prevLevel := m.level
+	m.level = m.alpha*value + (1-m.alpha)*(m.level+m.trend)
+	m.trend = m.beta*(m.level-prevLevel) + (1-m.beta)*m.trend
+	return true
+}
+
+// At returns the smoothed value at the current point.
+func (m *Smoothed) At() (uint64, float64) {
+	return m.timestamp, m.level
+}
+
+// Forecast returns the value expected steps points after the current one.
+func (m *Smoothed) Forecast(steps int) float64 {
+	return m.level + float64(steps)*m.trend
+}
+
+func (m *Smoothed) Err() error {
+	return m.r.err
+}
+
+// RunningStats are the mean and standard deviation of the points seen so
+// far, maintained with Welford's algorithm.
+type RunningStats struct {
+	Count  uint64
+	Mean   float64
+	StdDev float64
+	m2     float64
+}
+
+func (r *RunningStats) add(value float64) {
+	r.Count++
+	delta := value - r.Mean
+	r.Mean += delta / float64(r.Count)
+	r.m2 += delta * (value - r.Mean)
+	r.StdDev = math.Sqrt(r.m2 / float64(r.Count))
+}
+
+// AnomalyFunc reports whether a point is anomalous given the statistics of
+// the points before it.
+type AnomalyFunc func(timestamp uint64, value float64, stats RunningStats) bool
+
+// ZScore flags points more than threshold standard deviations away from the
+// running mean, once at least minCount points have been seen.
+func ZScore(threshold float64, minCount uint64) AnomalyFunc {
+	return func(timestamp uint64, value float64, stats RunningStats) bool {
+		if stats.Count < minCount || stats.StdDev == 0 {
+			return false
+		}
+		return math.Abs(value-stats.Mean) > threshold*stats.StdDev
+	}
+}
+
+// FindAnomalies decodes the points in [from, to] in a single pass and
+// returns the timestamps of those flagged by f. It reads from a Snapshot
+// and leaves the read position of s alone.
+func FindAnomalies(s *Series, from, to uint64, f AnomalyFunc) ([]uint64, error) {
+	r := rangeReader{s: s.Snapshot(), from: from, to: to}
+	var stats RunningStats
+	var res []uint64
+	for {
+		timestamp, value, ok := r.next()
+		if !ok {
+			return res, r.err
+		}
+		if f(timestamp, value, stats) {
+			res = append(res, timestamp)
+		}
+		stats.add(value)
+	}
+}
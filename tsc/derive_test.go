@@ -0,0 +1,116 @@
+package tsc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDerivativeIntegralResolution(t *testing.T) {
+	for _, res := range []time.Duration{0, time.Second, time.Millisecond, time.Microsecond} {
+		unit := uint64(1)
+		if res > 0 {
+			unit = uint64(time.Second / res)
+		}
+		s := NewSeries(WithResolution(res))
+		// rises 10 per second, sampled every 2 seconds
+		for i := uint64(0); i <= 5; i++ {
+			s.Append(1700000000*unit+2*i*unit, 10*float64(2*i))
+		}
+
+		d := NewDerivative(s.Snapshot(), 0, 1<<63)
+		n := 0
+		for ; d.Next(); n++ {
+			if _, rate := d.At(); rate != 10 {
+				t.Fatalf("resolution %v: rate %v", res, rate)
+			}
+		}
+		if d.Err() != nil || n != 5 {
+			t.Fatal(res, d.Err(), n)
+		}
+
+		in := NewIntegral(s.Snapshot(), 0, 1<<63)
+		for in.Next() {
+		}
+		// from 0 to 100 over 10 seconds
+		if _, sum := in.At(); in.Err() != nil || sum != 500 {
+			t.Fatalf("resolution %v: integral %v %v", res, sum, in.Err())
+		}
+	}
+}
+
+func TestDerivativeIntegralReadPosition(t *testing.T) {
+	s := NewSeries()
+	for i := uint64(0); i < 10; i++ {
+		s.Append(1440583200+60*i, float64(i))
+	}
+	if _, _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDerivative(s, 0, 1<<63)
+	n := 0
+	for ; d.Next(); n++ {
+	}
+	in := NewIntegral(s, 0, 1<<63)
+	for in.Next() {
+	}
+	// both start from the first point, whatever has been read from s
+	if n != 9 || d.Err() != nil || in.Err() != nil {
+		t.Fatal(n, d.Err(), in.Err())
+	}
+	if _, sum := in.At(); sum != 81*60/2 {
+		t.Fatal(sum)
+	}
+	if timestamp, _, err := s.Read(); err != nil || timestamp != 1440583260 {
+		t.Fatal("the iterators moved the read position", timestamp, err)
+	}
+}
+
+func TestSmoothedReadPosition(t *testing.T) {
+	s := NewSeries()
+	for i := uint64(0); i < 10; i++ {
+		s.Append(1440583200+60*i, 10)
+	}
+	s.Append(1440583200+600, 20)
+	if _, _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	m := NewEWMA(s, 0, 1<<63, 0.5)
+	n := 0
+	for ; m.Next(); n++ {
+	}
+	if _, level := m.At(); n != 11 || m.Err() != nil || level != 15 {
+		t.Fatal(n, level, m.Err())
+	}
+	h := NewHolt(s, 0, 1<<63, 0.5, 0.5)
+	for h.Next() {
+	}
+	if h.Err() != nil || h.Forecast(1) <= 15 {
+		t.Fatal("trend not picked up", h.Forecast(1), h.Err())
+	}
+	if timestamp, _, err := s.Read(); err != nil || timestamp != 1440583260 {
+		t.Fatal("the iterators moved the read position", timestamp, err)
+	}
+}
+
+func TestFindAnomalies(t *testing.T) {
+	s := NewSeries()
+	for i := uint64(0); i < 100; i++ {
+		v := float64(10 + i%3)
+		if i == 50 || i == 80 {
+			v = 100
+		}
+		s.Append(1440583200+60*i, v)
+	}
+	if _, _, err := s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		anomalies, err := FindAnomalies(s, 0, 1<<63, ZScore(3, 10))
+		if err != nil || len(anomalies) != 2 || anomalies[0] != 1440583200+60*50 || anomalies[1] != 1440583200+60*80 {
+			t.Fatal(anomalies, err)
+		}
+	}
+	if timestamp, _, err := s.Read(); err != nil || timestamp != 1440583260 {
+		t.Fatal("FindAnomalies moved the read position", timestamp, err)
+	}
+}
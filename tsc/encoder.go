@@ -0,0 +1,100 @@
+package tsc
+
+import (
+	"github.com/huangaz/tsc/bitUtil"
+	"io"
+)
+
+// Encoder compresses points. It is the write side of a Series on its own,
+// so nothing can read from it and disturb its state.
+type Encoder struct {
+	s Series
+}
+
+// NewEncoder returns an empty Encoder configured by opts.
+func NewEncoder(opts ...Option) *Encoder {
+	return &Encoder{s: *NewSeries(opts...)}
+}
+
+// Append adds a point and returns the number of bits it took.
+func (e *Encoder) Append(timestamp uint64, value float64) uint64 {
+	return e.s.Append(timestamp, value)
+}
+
+// Flush stores the points held back, such as the run held back by
+// WithConstantRuns, as Series.Flush does.
+func (e *Encoder) Flush() {
+	e.s.Flush()
+}
+
+// Bytes returns a copy of the encoded stream. Only the first NumBits bits
+// are meaningful.
+func (e *Encoder) Bytes() []byte {
+	return e.s.Bs.Bytes()
+}
+
+// NumBits returns the length of the encoded stream in bits.
+func (e *Encoder) NumBits() uint64 {
+	return e.s.Bs.NumBits
+}
+
+// Decoder returns a Decoder over the points appended so far. Appending
+// afterwards does not affect it.
+func (e *Encoder) Decoder() *Decoder {
+	return &Decoder{s: *e.s.Snapshot()}
+}
+
+func (e *Encoder) String() string {
+	return e.s.String()
+}
+
+// Decoder decompresses points written by an Encoder or a Series.
+type Decoder struct {
+	s Series
+
+	// see NewStreamDecoder()
+	src    io.Reader
+	unread uint64
+	buf    []byte
+}
+
+// NewDecoder returns a Decoder over the first numBits bits of data, which
+// must have been encoded with the same codec options as opts. The bit count
+// is needed because the padding after the last point would otherwise decode
+// as more points. data is not copied and must not be modified while the
+// Decoder is in use.
+func NewDecoder(data []byte, numBits uint64, opts ...Option) (*Decoder, error) {
+	bs, err := bitUtil.FromBytes(data, numBits)
+	if err != nil {
+		return nil, err
+	}
+	d := &Decoder{s: *NewSeries(opts...)}
+	d.s.Bs = *bs
+	return d, nil
+}
+
+func (d *Decoder) Read() (timestamp uint64, value float64, err error) {
+	if err := d.load(); err != nil {
+		return 0, 0, err
+	}
+	return d.s.Read()
+}
+
+// Done reports whether every point has been read.
+func (d *Decoder) Done() bool {
+	return d.unread == 0 && d.s.done()
+}
+
+// ReadAppend reads the remaining points and appends them to timestamps and
+// values, see Series.ReadAppend.
+func (d *Decoder) ReadAppend(timestamps []uint64, values []float64) ([]uint64, []float64, error) {
+	for !d.Done() {
+		timestamp, value, err := d.Read()
+		if err != nil {
+			return timestamps, values, err
+		}
+		timestamps = append(timestamps, timestamp)
+		values = append(values, value)
+	}
+	return timestamps, values, nil
+}
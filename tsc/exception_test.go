@@ -0,0 +1,45 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestReportByException(t *testing.T) {
+	const t0 = 1440583200
+	values := []float64{10, 10.2, 10.4, 12, 12.1, 12.2, 12.3, 12.4, 12.1, 12.2}
+	var s Series
+	s.SetReportByException(0.5, 300)
+	for i, v := range values {
+		s.Append(t0+60*uint64(i), v)
+	}
+	s.Flush()
+
+	// 10.4 is kept as the end of the first level, 12.1 is stored because
+	// maxInterval passed and the trailing 12.2 is stored by Flush
+	want := []Point{{t0, 10}, {t0 + 120, 10.4}, {t0 + 180, 12}, {t0 + 480, 12.1}, {t0 + 540, 12.2}}
+	timestamps, got, err := s.ReadAppend(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v at %v, want %v", got, timestamps, want)
+	}
+	for i := range want {
+		if timestamps[i] != want[i].Timestamp || got[i] != want[i].Value {
+			t.Fatalf("point %d is (%d, %v), want (%d, %v)",
+				i, timestamps[i], got[i], want[i].Timestamp, want[i].Value)
+		}
+	}
+}
+
+func TestReportByExceptionFlushWithoutHeld(t *testing.T) {
+	var s Series
+	s.SetReportByException(1, 300)
+	s.Append(1440583200, 1)
+	s.Append(1440583260, 5)
+	s.Flush()
+	s.Flush()
+	if timestamps, _, err := s.ReadAppend(nil, nil); err != nil || len(timestamps) != 2 {
+		t.Fatal(timestamps, err)
+	}
+}
@@ -0,0 +1,140 @@
+package tsc
+
+import (
+	"github.com/huangaz/tsc/bitUtil"
+	"math"
+)
+
+const (
+	LEADING_ZEROS_LENGTH_BITS_32 = 5
+	BLOCK_SIZE_LENGTH_BITS_32    = 5
+	MAX_LEADING_ZEROS_LENGTH_32  = (1 << LEADING_ZEROS_LENGTH_BITS_32) - 1
+)
+
+// Float32Series compresses float32 values using only 32-bit value
+// operations, for constrained devices. Build with the tsc32 tag to make the
+// bit stream itself work in 32-bit words as well. Timestamps are encoded
+// as in Series.
+type Float32Series struct {
+	// holds the stream and the timestamp state
+	s Series
+
+	prevValueWrite    uint32
+	prevLeadingWrite  uint64
+	prevTrailingWrite uint64
+
+	prevValueRead    uint32
+	prevLeadingRead  uint64
+	prevTrailingRead uint64
+}
+
+// Bs returns the encoded stream.
+func (f *Float32Series) Bs() *bitUtil.BitStream {
+	return &f.s.Bs
+}
+
+// Append adds a point and returns the number of bits it took.
+func (f *Float32Series) Append(timestamp uint64, value float32) uint64 {
+	before := f.s.Bs.NumBits
+	f.s.appendTimestamp(timestamp)
+	f.appendValue(math.Float32bits(value))
+	f.s.count++
+	return f.s.Bs.NumBits - before
+}
+
+func (f *Float32Series) Read() (uint64, float32, error) {
+	if f.s.runRead > 0 || f.s.readRun() {
+		return f.s.readRunPoint(), math.Float32frombits(f.prevValueRead), nil
+	}
+	timestamp, err := f.s.readNextTimestamp()
+	if err != nil {
+		return 0, 0, err
+	}
+	value, err := f.readNextValue()
+	if err != nil {
+		return 0, 0, err
+	}
+	return timestamp, math.Float32frombits(value), nil
+}
+
+func (f *Float32Series) appendValue(value uint32) {
+	bs := &f.s.Bs
+	xorWithPrev := value ^ f.prevValueWrite
+	if xorWithPrev == 0 {
+		bs.AddValue32(0, 1)
+		return
+	}
+	bs.AddValue32(1, 1)
+
+	leading := bitUtil.Clz32(xorWithPrev)
+	trailing := bitUtil.Ctz32(xorWithPrev)
+	if leading > MAX_LEADING_ZEROS_LENGTH_32 {
+		leading = MAX_LEADING_ZEROS_LENGTH_32
+	}
+
+	blockSize := 32 - leading - trailing
+	expectedSize := LEADING_ZEROS_LENGTH_BITS_32 + BLOCK_SIZE_LENGTH_BITS_32 + blockSize
+	prevBlockInformationSize := 32 - f.prevLeadingWrite - f.prevTrailingWrite
+
+	if leading >= f.prevLeadingWrite && trailing >= f.prevTrailingWrite && prevBlockInformationSize < expectedSize {
+		bs.AddValue32(1, 1)
+		bs.AddValue32(xorWithPrev>>f.prevTrailingWrite, prevBlockInformationSize)
+	} else {
+		bs.AddValue32(0, 1)
+		bs.AddValue32(uint32(leading), LEADING_ZEROS_LENGTH_BITS_32)
+		bs.AddValue32(uint32(blockSize-BLOCK_SIZE_ADJUSTMENT), BLOCK_SIZE_LENGTH_BITS_32)
+		bs.AddValue32(xorWithPrev>>trailing, blockSize)
+		f.prevLeadingWrite = leading
+		f.prevTrailingWrite = trailing
+	}
+	f.prevValueWrite = value
+}
+
+func (f *Float32Series) readNextValue() (uint32, error) {
+	bs := &f.s.Bs
+	nonZeroValue, err := bs.ReadValue32(1)
+	if err != nil {
+		return 0, err
+	}
+	if nonZeroValue == 0 {
+		return f.prevValueRead, nil
+	}
+
+	usePrevBlockInformation, err := bs.ReadValue32(1)
+	if err != nil {
+		return 0, err
+	}
+
+	var xorValue uint32
+	if usePrevBlockInformation == 1 {
+		xorValue, err = bs.ReadValue32(32 - f.prevLeadingRead - f.prevTrailingRead)
+		if err != nil {
+			return 0, err
+		}
+		xorValue <<= f.prevTrailingRead
+	} else {
+		leading, err := bs.ReadValue32(LEADING_ZEROS_LENGTH_BITS_32)
+		if err != nil {
+			return 0, err
+		}
+		blockSize, err := bs.ReadValue32(BLOCK_SIZE_LENGTH_BITS_32)
+		if err != nil {
+			return 0, err
+		}
+		blockSize += BLOCK_SIZE_ADJUSTMENT
+		if leading+blockSize > 32 {
+			return 0, errXorBlock
+		}
+		f.prevTrailingRead = 32 - uint64(leading) - uint64(blockSize)
+		xorValue, err = bs.ReadValue32(uint64(blockSize))
+		if err != nil {
+			return 0, err
+		}
+		xorValue <<= f.prevTrailingRead
+		f.prevLeadingRead = uint64(leading)
+	}
+
+	value := xorValue ^ f.prevValueRead
+	f.prevValueRead = value
+	return value, nil
+}
@@ -0,0 +1,47 @@
+package tsc
+
+// Gap is an interval without samples, bounded by the samples around it.
+type Gap struct {
+	From, To uint64
+}
+
+// Gaps returns the intervals within [from, to] longer than maxInterval
+// without a sample. The ends of the range count as samples, so a series
+// that starts late or stops early has a gap at that end. Like All, it reads
+// from a Snapshot and leaves the read position of s alone.
+//
+// Each value is encoded against the one before it and interleaved with the
+// timestamps, so the values in range are decoded along with the timestamps
+// and Gaps costs about as much as ReadRange over [from, to]. A range
+// entirely before the first or after the last point stored is answered
+// from those timestamps without decoding.
+func (s *Series) Gaps(from, to, maxInterval uint64) ([]Gap, error) {
+	var gaps []Gap
+	if s.count == 0 || to < s.firstTime || from > s.prevTimeWrite {
+		if to > from && to-from > maxInterval {
+			gaps = append(gaps, Gap{from, to})
+		}
+		return gaps, nil
+	}
+	snap := s.Snapshot()
+	snap.Seek(from)
+	r := rangeReader{s: snap, from: from, to: to}
+	prev := from
+	for {
+		timestamp, _, ok := r.next()
+		if !ok {
+			break
+		}
+		if timestamp-prev > maxInterval {
+			gaps = append(gaps, Gap{prev, timestamp})
+		}
+		prev = timestamp
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if to > prev && to-prev > maxInterval {
+		gaps = append(gaps, Gap{prev, to})
+	}
+	return gaps, nil
+}
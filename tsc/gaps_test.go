@@ -0,0 +1,70 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestGaps(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries()
+	for _, timestamp := range []uint64{t0, t0 + 60, t0 + 600, t0 + 660, t0 + 720} {
+		s.Append(timestamp, 1)
+	}
+	tests := []struct {
+		from, to uint64
+		want     []Gap
+	}{
+		{t0 - 100, t0 + 800, []Gap{{t0 + 60, t0 + 600}}},
+		{t0 - 200, t0 + 1000, []Gap{{t0 - 200, t0}, {t0 + 60, t0 + 600}, {t0 + 720, t0 + 1000}}},
+		{t0 + 600, t0 + 720, nil},
+		{t0 + 100, t0 + 300, []Gap{{t0 + 100, t0 + 300}}},
+	}
+	for _, test := range tests {
+		gaps, err := s.Gaps(test.from, test.to, 120)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gaps) != len(test.want) {
+			t.Fatalf("[%d, %d]: got %v, want %v", test.from, test.to, gaps, test.want)
+		}
+		for i := range gaps {
+			if gaps[i] != test.want[i] {
+				t.Fatalf("[%d, %d]: got %v, want %v", test.from, test.to, gaps, test.want)
+			}
+		}
+	}
+	if timestamp, _, err := s.Read(); err != nil || timestamp != t0 {
+		t.Fatal("Gaps moved the read position", timestamp, err)
+	}
+}
+
+func TestGapsOutsideSeries(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries()
+	for i := uint64(0); i < 10; i++ {
+		s.Append(t0+60*i, 1)
+	}
+	// a broken stream shows which ranges are decoded
+	s.Bs.NumBits -= 3
+	if _, err := s.Gaps(t0, t0+1000, 120); err == nil {
+		t.Fatal("broken stream decoded")
+	}
+	tests := []struct {
+		from, to uint64
+		want     []Gap
+	}{
+		{t0 - 1000, t0 - 1, []Gap{{t0 - 1000, t0 - 1}}},
+		{t0 + 541, t0 + 2000, []Gap{{t0 + 541, t0 + 2000}}},
+		{t0 + 600, t0 + 700, nil},
+		{t0 + 700, t0 + 600, nil},
+	}
+	for _, test := range tests {
+		gaps, err := s.Gaps(test.from, test.to, 120)
+		if err != nil || len(gaps) != len(test.want) || (len(gaps) > 0 && gaps[0] != test.want[0]) {
+			t.Fatalf("[%d, %d]: got %v %v, want %v", test.from, test.to, gaps, err, test.want)
+		}
+	}
+	if gaps, err := NewSeries().Gaps(t0, t0+1000, 120); err != nil || len(gaps) != 1 || gaps[0] != (Gap{t0, t0 + 1000}) {
+		t.Fatal(gaps, err)
+	}
+}
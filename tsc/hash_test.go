@@ -0,0 +1,32 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	a, b := NewSeries(), NewSeries()
+	for i := 0; i < 2000; i++ {
+		a.Append(1440583200+60*uint64(i), 42)
+		b.Append(1440583200+60*uint64(i), 42)
+	}
+	if a.Hash() != b.Hash() {
+		t.Fatal("identical series hash differently")
+	}
+	if _, _, err := a.Read(); err != nil || a.Hash() != b.Hash() {
+		t.Fatal("reading changed the hash", err)
+	}
+	b.Append(1440583200+60*2000, 42)
+	a.Append(1440583200+60*2000, 43)
+	if a.Hash() == b.Hash() {
+		t.Fatal("different last values hash the same")
+	}
+
+	// the same bytes with a different bit length
+	var c, d Series
+	c.Bs.AddValueToBitStream(0, 1)
+	d.Bs.AddValueToBitStream(0, 2)
+	if c.Hash() == d.Hash() {
+		t.Fatal("bit length is not part of the hash")
+	}
+}
@@ -0,0 +1,275 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+const BLOCK_FORMAT_VERSION = 1
+
+var (
+	errBlockHeader  = errors.New("Invalid block header")
+	errBlockVersion = errors.New("Unsupported block format version")
+)
+
+// flags of a block header
+const (
+	blockChecksum = 1 << iota
+	blockValueScale
+	blockScaleOnRead
+	blockException
+)
+
+// BlockHeader describes a block written by EncodeBlock: the format and
+// codecs needed to decode it and a summary of its points.
+type BlockHeader struct {
+	Version    uint8
+	TimeCodec  uint8
+	ValueCodec uint8
+	Resolution time.Duration
+	// the first and last points as stored, before the ValueScale; zero
+	// when there are none
+	FirstTimestamp uint64
+	FirstValue     float64
+	LastTimestamp  uint64
+	LastValue      float64
+	Count          uint64
+	NumBits        uint64
+	// see SetValueScale, 1 and 0 if it was not called
+	ValueMultiplier float64
+	ValueOffset     float64
+	// see SetReportByException: values within ExceptionBand of the one
+	// before may have been skipped
+	ReportByException    bool
+	ExceptionBand        float64
+	ExceptionMaxInterval uint64
+
+	// decoding options, see the option of the same name
+	checksum        bool
+	hasValueScale   bool
+	scaleOnRead     bool
+	minRun          uint64
+	defaultDelta    uint64
+	rebaselineGap   uint64
+	restartInterval uint64
+	restarts        []restartPoint
+}
+
+/*
+* block		bytes
+* version	1
+* time codec	1
+* value codec	1
+* flags		1
+* null value	8, NULL_VALUE_BITS
+* resolution, first, last, count, numBits, minRun, defaultDelta,
+* rebaselineGap, restartInterval	uvarint each
+* first, last value	8 + 8 if count > 0
+* value scale	8 + 8, multiplier and offset if flags has blockValueScale
+* exception	8 + uvarint, band and maxInterval if flags has
+*		blockException
+* restarts	uvarint count, then per restart the differences from
+*		the previous one of offset (uvarint), index (uvarint) and
+*		timestamp (varint)
+* stream	(numBits + 7) / 8
+ */
+
+// EncodeBlock returns the points appended so far preceded by a header
+// recording the format version, the codecs and options needed to decode
+// them, and their count and time span, so the block can be validated and
+// read back with NewBlockDecoder without knowing how it was written. The
+// first and last points are also stored uncompressed, so queries about the
+// boundaries of a block need not decode it. The restarts of
+// WithRestartInterval are recorded too, so the Decoder can Seek, as are the value that marks nulls, the ValueScale and the
+// settings of SetReportByException. Points held back, see Flush, are not
+// included.
+func (s *Series) EncodeBlock() []byte {
+	var flags byte
+	if s.checksum {
+		flags |= blockChecksum
+	}
+	if s.hasValueScale {
+		flags |= blockValueScale
+	}
+	if s.scaleOnRead {
+		flags |= blockScaleOnRead
+	}
+	if s.exceptionEnabled {
+		flags |= blockException
+	}
+	data := make([]byte, 0, 96+8*len(s.restarts)+s.Bs.Len())
+	data = append(data, BLOCK_FORMAT_VERSION, s.timeCodecID, s.valueCodecID, flags)
+	data = binary.BigEndian.AppendUint64(data, NULL_VALUE_BITS)
+	var first, last uint64
+	if s.count > 0 {
+		first, last = s.firstTime, s.prevTimeWrite
+	}
+	for _, v := range []uint64{
+		uint64(s.resolution), first, last, s.count, s.Bs.NumBits,
+		s.minRun, s.defaultDelta, s.rebaselineGap, s.restartInterval,
+	} {
+		data = binary.AppendUvarint(data, v)
+	}
+	if s.count > 0 {
+		data = binary.BigEndian.AppendUint64(data, s.firstValue())
+		data = binary.BigEndian.AppendUint64(data, s.lastValueWrite)
+	}
+	if s.hasValueScale {
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(s.valueMultiplier))
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(s.valueOffset))
+	}
+	if s.exceptionEnabled {
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(s.exceptionBand))
+		data = binary.AppendUvarint(data, s.exceptionMaxInterval)
+	}
+	data = binary.AppendUvarint(data, uint64(len(s.restarts)))
+	var prev restartPoint
+	for _, r := range s.restarts {
+		data = binary.AppendUvarint(data, r.offset-prev.offset)
+		data = binary.AppendUvarint(data, r.index-prev.index)
+		data = binary.AppendVarint(data, int64(r.timestamp-prev.timestamp))
+		prev = r
+	}
+	return append(data, s.Bs.Bytes()...)
+}
+
+// firstValue returns the bits of the first value stored, read from a copy
+// of s so the read position of s is kept.
+func (s *Series) firstValue() uint64 {
+	r := *s
+	r.resetRead()
+	r.scaleOnRead = false
+	// a stream written by Append always decodes
+	_, value, _ := r.Read()
+	return math.Float64bits(value)
+}
+
+// EncodeBlock returns the points with a header, see Series.EncodeBlock.
+func (e *Encoder) EncodeBlock() []byte {
+	return e.s.EncodeBlock()
+}
+
+// ReadBlockHeader parses the header of a block written by EncodeBlock and
+// returns it with the length of the header in bytes. It fails if the
+// version or the codecs are unknown, nulls are marked by another value than
+// NULL_VALUE_BITS or the stream is shorter than the header says.
+func ReadBlockHeader(block []byte) (BlockHeader, int, error) {
+	r := stateReader{data: block}
+	h := BlockHeader{Version: r.byte(), ValueMultiplier: 1}
+	if r.err == nil && h.Version != BLOCK_FORMAT_VERSION {
+		return h, 0, errBlockVersion
+	}
+	h.TimeCodec = r.byte()
+	h.ValueCodec = r.byte()
+	flags := r.byte()
+	if null := r.uint64(); r.err == nil && null != NULL_VALUE_BITS {
+		return h, 0, errBlockHeader
+	}
+	h.Resolution = time.Duration(r.uvarint())
+	h.FirstTimestamp = r.uvarint()
+	h.LastTimestamp = r.uvarint()
+	h.Count = r.uvarint()
+	h.NumBits = r.uvarint()
+	h.minRun = r.uvarint()
+	h.defaultDelta = r.uvarint()
+	h.rebaselineGap = r.uvarint()
+	h.restartInterval = r.uvarint()
+	if h.Count > 0 {
+		h.FirstValue = math.Float64frombits(r.uint64())
+		h.LastValue = math.Float64frombits(r.uint64())
+	}
+	if flags&blockValueScale != 0 {
+		h.ValueMultiplier = math.Float64frombits(r.uint64())
+		h.ValueOffset = math.Float64frombits(r.uint64())
+	}
+	if h.ReportByException = flags&blockException != 0; h.ReportByException {
+		h.ExceptionBand = math.Float64frombits(r.uint64())
+		h.ExceptionMaxInterval = r.uvarint()
+	}
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		// each restart takes at least 3 bytes
+		return h, 0, errBlockHeader
+	}
+	h.restarts = make([]restartPoint, 0, n)
+	var prev restartPoint
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		prev.offset += r.uvarint()
+		prev.index += r.uvarint()
+		prev.timestamp += uint64(r.varint())
+		if prev.offset > h.NumBits || prev.index >= h.Count {
+			return h, 0, errBlockHeader
+		}
+		h.restarts = append(h.restarts, prev)
+	}
+	if r.err != nil || flags >= blockException<<1 || h.NumBits > uint64(len(r.data))*8 {
+		return h, 0, errBlockHeader
+	}
+	if _, ok := timeCodecs.lookup(h.TimeCodec); !ok {
+		return h, 0, errBlockHeader
+	}
+	if _, ok := valueCodecs.lookup(h.ValueCodec); !ok {
+		return h, 0, errBlockHeader
+	}
+	h.checksum = flags&blockChecksum != 0
+	h.hasValueScale = flags&blockValueScale != 0
+	h.scaleOnRead = flags&blockScaleOnRead != 0
+	return h, len(block) - len(r.data), nil
+}
+
+// SeriesInfo is the summary of a block a store needs to index it, see
+// ReadInfo.
+type SeriesInfo struct {
+	Version    uint8
+	TimeCodec  uint8
+	ValueCodec uint8
+	Count      uint64
+	// zero when there are no points
+	FirstTimestamp uint64
+	LastTimestamp  uint64
+	// whether WithChecksum was set, so Read verifies the points
+	Checksum bool
+}
+
+// ReadInfo returns the summary of a block written by EncodeBlock from its
+// header alone, without decoding the points. It fails as ReadBlockHeader.
+func ReadInfo(block []byte) (SeriesInfo, error) {
+	h, _, err := ReadBlockHeader(block)
+	if err != nil {
+		return SeriesInfo{}, err
+	}
+	return SeriesInfo{
+		Version:        h.Version,
+		TimeCodec:      h.TimeCodec,
+		ValueCodec:     h.ValueCodec,
+		Count:          h.Count,
+		FirstTimestamp: h.FirstTimestamp,
+		LastTimestamp:  h.LastTimestamp,
+		Checksum:       h.checksum,
+	}, nil
+}
+
+// NewBlockDecoder returns a Decoder over a block written by EncodeBlock,
+// configured from its header, and the header. block is not copied and must
+// not be modified while the Decoder is in use.
+func NewBlockDecoder(block []byte) (*Decoder, BlockHeader, error) {
+	h, n, err := ReadBlockHeader(block)
+	if err != nil {
+		return nil, h, err
+	}
+	d, err := NewDecoder(block[n:], h.NumBits)
+	if err != nil {
+		return nil, h, err
+	}
+	d.s.timeCodecID, d.s.valueCodecID = h.TimeCodec, h.ValueCodec
+	d.s.resolution, d.s.checksum, d.s.minRun = h.Resolution, h.checksum, h.minRun
+	d.s.defaultDelta, d.s.rebaselineGap = h.defaultDelta, h.rebaselineGap
+	d.s.restartInterval, d.s.restarts = h.restartInterval, h.restarts
+	d.s.valueMultiplier, d.s.valueOffset = h.ValueMultiplier, h.ValueOffset
+	d.s.hasValueScale, d.s.scaleOnRead = h.hasValueScale, h.scaleOnRead
+	d.s.exceptionEnabled, d.s.exceptionBand = h.ReportByException, h.ExceptionBand
+	d.s.exceptionMaxInterval = h.ExceptionMaxInterval
+	return d, h, nil
+}
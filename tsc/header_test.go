@@ -0,0 +1,135 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBlockDecoderSeek(t *testing.T) {
+	s := NewSeries(WithRestartInterval(16), WithChecksum())
+	for i := uint64(0); i < 1000; i++ {
+		s.Append(1440583200+60*i, float64(i%7))
+	}
+	d, h, err := NewBlockDecoder(s.EncodeBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Count != 1000 || len(d.s.restarts) != len(s.restarts) {
+		t.Fatal(h.Count, len(d.s.restarts), len(s.restarts))
+	}
+	for _, i := range []uint64{999, 0, 500, 17, 16, 15} {
+		target := 1440583200 + 60*i
+		if err := d.Seek(target); err != nil {
+			t.Fatal(err)
+		}
+		// at most an interval of points before the target
+		for n := 0; ; n++ {
+			timestamp, value, err := d.Read()
+			if err != nil || n > 16 {
+				t.Fatal(i, n, err)
+			}
+			if timestamp == target {
+				if value != float64(i%7) {
+					t.Fatal(i, value)
+				}
+				break
+			}
+		}
+	}
+
+	truncated := s.EncodeBlock()
+	_, n, _ := ReadBlockHeader(truncated)
+	if _, _, err := ReadBlockHeader(truncated[:n-1]); err == nil {
+		t.Fatal("truncated restarts accepted")
+	}
+}
+
+func TestBlockHeaderValueSettings(t *testing.T) {
+	s := NewSeries()
+	s.SetValueScale(0.001, -40)
+	s.SetScaleOnRead(true)
+	s.SetReportByException(2, 600)
+	for i := uint64(0); i < 10; i++ {
+		s.Append(1440583200+60*i, float64(20000+1000*(i%2)))
+	}
+	s.AppendNull(1440583200 + 60*10)
+	block := s.EncodeBlock()
+	d, h, err := NewBlockDecoder(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ValueMultiplier != 0.001 || h.ValueOffset != -40 ||
+		!h.ReportByException || h.ExceptionBand != 2 || h.ExceptionMaxInterval != 600 {
+		t.Fatalf("%+v", h)
+	}
+	s.resetRead()
+	for i := 0; i < 11; i++ {
+		_, want, _ := s.Read()
+		_, value, err := d.Read()
+		if err != nil || math.Float64bits(value) != math.Float64bits(want) {
+			t.Fatal(i, value, err, want)
+		}
+	}
+
+	if _, h, err := NewBlockDecoder(NewSeries().EncodeBlock()); err != nil ||
+		h.ValueMultiplier != 1 || h.ValueOffset != 0 || h.ReportByException {
+		t.Fatalf("defaults: %+v %v", h, err)
+	}
+
+	// a block marking nulls with another value would be read wrong
+	block[4] ^= 1
+	if _, _, err := ReadBlockHeader(block); err == nil {
+		t.Fatal("other null value accepted")
+	}
+}
+
+func TestReadInfo(t *testing.T) {
+	s := NewSeries(WithChecksum(), WithTimeCodec(TIME_CODEC_DOD64))
+	for i := uint64(0); i < 100; i++ {
+		s.Append(1440583200+60*i, float64(i))
+	}
+	block := s.EncodeBlock()
+	info, err := ReadInfo(block)
+	want := SeriesInfo{BLOCK_FORMAT_VERSION, TIME_CODEC_DOD64, VALUE_CODEC_XOR, 100, 1440583200, 1440583200 + 60*99, true}
+	if err != nil || info != want {
+		t.Fatalf("%+v %v", info, err)
+	}
+	// the header is checked against the stream like ReadBlockHeader does
+	_, n, _ := ReadBlockHeader(block)
+	if _, err := ReadInfo(block[:n]); err == nil {
+		t.Fatal("block without its stream accepted")
+	}
+	block[0] = BLOCK_FORMAT_VERSION + 1
+	if _, err := ReadInfo(block); err != errBlockVersion {
+		t.Fatal(err)
+	}
+}
+
+func TestBlockHeaderBoundaries(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithChecksum(), WithValueCodec(VALUE_CODEC_CHIMP128)},
+		{WithConstantRuns(4)},
+	} {
+		s := NewSeries(opts...)
+		s.SetValueScale(10, 0)
+		s.SetScaleOnRead(true)
+		for i := uint64(0); i < 100; i++ {
+			s.Append(1440583200+60*i, float64(1+i/50))
+		}
+		s.Flush()
+		// the read position of s is kept
+		s.Read()
+		h, _, err := ReadBlockHeader(s.EncodeBlock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.FirstTimestamp != 1440583200 || h.FirstValue != 1 ||
+			h.LastTimestamp != 1440583200+60*99 || h.LastValue != 2 {
+			t.Fatalf("%+v", h)
+		}
+		if timestamp, value, err := s.Read(); err != nil || timestamp != 1440583200+60 || value != 10 {
+			t.Fatal(timestamp, value, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package tsc
+
+import (
+	"github.com/huangaz/tsc/bitUtil"
+)
+
+// how IntSeries encodes values
+const (
+	INT_ENCODING_DOD   = 0
+	INT_ENCODING_DELTA = 1
+)
+
+/*
+* zigzag value	tag	value bits
+* 0		0	0
+* 1,128		10	7
+* 129,65536	110	16
+* others <2^32	1110	32
+* others	1111	64
+ */
+var intValueBits = []uint64{7, 16, 32, 64}
+
+// IntSeries compresses int64 values such as counters, storing each value as
+// the zigzag-encoded difference from the previous delta (INT_ENCODING_DOD,
+// the default, best for counters growing at a steady rate) or from the
+// previous value (INT_ENCODING_DELTA, best for gauges). Timestamps are
+// encoded as in Series.
+type IntSeries struct {
+	// holds the stream and the timestamp state
+	s        Series
+	encoding int
+
+	prevValueWrite int64
+	prevDeltaWrite int64
+	prevValueRead  int64
+	prevDeltaRead  int64
+}
+
+// NewIntSeries returns an empty IntSeries using encoding for values and
+// configured by opts. Value codec options do not apply.
+func NewIntSeries(encoding int, opts ...Option) *IntSeries {
+	return &IntSeries{s: *NewSeries(opts...), encoding: encoding}
+}
+
+// Bs returns the encoded stream.
+func (i *IntSeries) Bs() *bitUtil.BitStream {
+	return &i.s.Bs
+}
+
+// Append adds a point and returns the number of bits it took.
+func (i *IntSeries) Append(timestamp uint64, value int64) uint64 {
+	before := i.s.Bs.NumBits
+	i.s.appendTimestamp(timestamp)
+	delta := value - i.prevValueWrite
+	diff := delta
+	if i.encoding == INT_ENCODING_DOD {
+		diff -= i.prevDeltaWrite
+	}
+	appendZigzag(&i.s.Bs, diff)
+	i.prevValueWrite, i.prevDeltaWrite = value, delta
+	i.s.count++
+	return i.s.Bs.NumBits - before
+}
+
+func (i *IntSeries) Read() (uint64, int64, error) {
+	if i.s.runRead > 0 || i.s.readRun() {
+		// a run repeats the difference, which is zero
+		timestamp := i.s.readRunPoint()
+		i.prevValueRead += i.runDelta()
+		return timestamp, i.prevValueRead, nil
+	}
+	timestamp, err := i.s.readNextTimestamp()
+	if err != nil {
+		return 0, 0, err
+	}
+	diff, err := readZigzag(&i.s.Bs)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i.encoding == INT_ENCODING_DOD {
+		i.prevDeltaRead += diff
+	} else {
+		i.prevDeltaRead = diff
+	}
+	i.prevValueRead += i.prevDeltaRead
+	return timestamp, i.prevValueRead, nil
+}
+
+// runDelta returns the delta of a point whose stored difference is zero.
+func (i *IntSeries) runDelta() int64 {
+	if i.encoding == INT_ENCODING_DOD {
+		return i.prevDeltaRead
+	}
+	i.prevDeltaRead = 0
+	return 0
+}
+
+func appendZigzag(bs *bitUtil.BitStream, value int64) {
+	zigzag := uint64(value<<1) ^ uint64(value>>63)
+	if zigzag == 0 {
+		bs.AddValueToBitStream(0, 1)
+		return
+	}
+	// there are no zeros, shift by one to fit in x number of bits
+	zigzag--
+	for n, bits := range intValueBits {
+		if bits == 64 || zigzag < 1<<bits {
+			if n < len(intValueBits)-1 {
+				// n+1 ones and a zero
+				bs.AddValueToBitStream((1<<(n+2))-2, uint64(n+2))
+			} else {
+				bs.AddValueToBitStream((1<<(n+1))-1, uint64(n+1))
+			}
+			bs.AddValueToBitStream(zigzag, bits)
+			return
+		}
+	}
+}
+
+func readZigzag(bs *bitUtil.BitStream) (int64, error) {
+	n, err := bs.ReadUnary(uint64(len(intValueBits)))
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	zigzag, err := bs.ReadValueFromBitStream(intValueBits[n-1])
+	if err != nil {
+		return 0, err
+	}
+	zigzag++
+	return int64(zigzag>>1) ^ -int64(zigzag&1), nil
+}
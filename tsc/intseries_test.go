@@ -0,0 +1,50 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntSeries(t *testing.T) {
+	const t0 = 1440583200
+	values := []int64{0, 1, 2, 3, 3, 3, -5, 200, 70000, 70001, 1 << 40,
+		math.MaxInt64, math.MinInt64, 0, -1, 129, 130}
+	for _, encoding := range []int{INT_ENCODING_DOD, INT_ENCODING_DELTA} {
+		s := NewIntSeries(encoding)
+		for i, v := range values {
+			s.Append(t0+60*uint64(i), v)
+		}
+		for i, v := range values {
+			timestamp, value, err := s.Read()
+			if err != nil || timestamp != t0+60*uint64(i) || value != v {
+				t.Fatalf("encoding %d, point %d: (%d, %d) %v, want %d", encoding, i, timestamp, value, err, v)
+			}
+		}
+		if !s.s.done() {
+			t.Fatalf("encoding %d: bits left", encoding)
+		}
+	}
+}
+
+func TestIntSeriesCounter(t *testing.T) {
+	const t0, points = 1440583200, 1000
+	for _, encoding := range []int{INT_ENCODING_DOD, INT_ENCODING_DELTA} {
+		s := NewIntSeries(encoding)
+		for i := 0; i < points; i++ {
+			// a counter growing by 10 per interval, or a constant gauge
+			v := int64(10 * i)
+			if encoding == INT_ENCODING_DELTA {
+				v = 42
+			}
+			s.Append(t0+60*uint64(i), v)
+		}
+		if bits := s.Bs().NumBits; bits > 2*points+100 {
+			t.Fatalf("encoding %d: %d bits for %d steady points", encoding, bits, points)
+		}
+		for i := 0; i < points; i++ {
+			if _, _, err := s.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
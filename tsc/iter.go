@@ -0,0 +1,117 @@
+package tsc
+
+import (
+	"iter"
+)
+
+// IterOption configures the iterator returned by All.
+type IterOption func(*iterConfig)
+
+type iterConfig struct {
+	where []func(float64) bool
+}
+
+// WhereValue makes All skip points whose value fails pred. When given more
+// than once, a point must pass every predicate. A failing value that
+// repeats over a run of points is tested once and the whole run skipped.
+func WhereValue(pred func(float64) bool) IterOption {
+	return func(c *iterConfig) {
+		c.where = append(c.where, pred)
+	}
+}
+
+func (c *iterConfig) match(value float64) bool {
+	for _, pred := range c.where {
+		if !pred(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// All returns an iterator over every point appended so far, for use with
+// range. It reads from a Snapshot, so it neither depends on nor moves the
+// read position of s. Iteration stops early if the stream is corrupt.
+func (s *Series) All(opts ...IterOption) iter.Seq2[uint64, float64] {
+	var cfg iterConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(yield func(uint64, float64) bool) {
+		snap := s.Snapshot()
+		for !snap.done() {
+			timestamp, value, err := snap.Read()
+			if err != nil {
+				return
+			}
+			if !cfg.match(value) {
+				snap.skipRun()
+				continue
+			}
+			if !yield(timestamp, value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator steps through the points of a series:
+//
+//	it := s.Iterator()
+//	for it.Next() {
+//		timestamp, value := it.At()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	s         *Series
+	d         *Decoder
+	timestamp uint64
+	value     float64
+	offset    uint64
+	err       error
+}
+
+// Iterator returns an Iterator over every point appended so far. Like All,
+// it reads from a Snapshot and leaves the read position of s alone.
+func (s *Series) Iterator() *Iterator {
+	return &Iterator{s: s.Snapshot()}
+}
+
+// Iterator returns an Iterator over the points not read yet. Reading from
+// d while iterating moves the iterator too.
+func (d *Decoder) Iterator() *Iterator {
+	return &Iterator{s: &d.s, d: d}
+}
+
+// Next moves to the next point and reports whether there is one.
+func (it *Iterator) Next() bool {
+	if it.err == nil && it.d != nil {
+		// a stream decoder loads the stream as it goes
+		it.err = it.d.load()
+	}
+	if it.err != nil || it.s.done() {
+		return false
+	}
+	it.offset = it.s.nextOffset()
+	it.timestamp, it.value, it.err = it.s.Read()
+	return it.err == nil
+}
+
+// Offset returns the bit offset in the stream at which the current point
+// starts, or at which decoding failed after Next returns false with an
+// error, to locate corruption.
+func (it *Iterator) Offset() uint64 {
+	return it.offset
+}
+
+func (it *Iterator) At() (uint64, float64) {
+	return it.timestamp, it.value
+}
+
+// Err returns the error that stopped Next early, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
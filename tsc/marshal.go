@@ -0,0 +1,262 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/huangaz/tsc/bitUtil"
+	"math"
+	"time"
+)
+
+const SERIES_STATE_VERSION = 1
+
+var errSeriesState = errors.New("Invalid series state")
+
+const (
+	stateValueScale = 1 << iota
+	stateScaleOnRead
+	stateException
+	stateHeld
+	stateInput
+	stateChecksum
+	stateConstantRuns
+)
+
+// MarshalBinary captures the encoded stream and everything needed to keep
+// appending to it, so a partially written series can be persisted and
+// resumed with UnmarshalBinary. Transforms are functions and are not
+// included; the read position is not included either.
+func (s *Series) MarshalBinary() ([]byte, error) {
+	stream := s.Bs.Bytes()
+	data := make([]byte, 0, 128+len(stream))
+	data = append(data, SERIES_STATE_VERSION, s.timeCodecID, s.valueCodecID)
+	data = binary.BigEndian.AppendUint64(data, s.Bs.NumBits)
+	data = append(data, stream...)
+
+	var flags byte
+	if s.hasValueScale {
+		flags |= stateValueScale
+	}
+	if s.scaleOnRead {
+		flags |= stateScaleOnRead
+	}
+	if s.exceptionEnabled {
+		flags |= stateException
+	}
+	if s.hasHeld {
+		flags |= stateHeld
+	}
+	if s.hasInput {
+		flags |= stateInput
+	}
+	if s.checksum {
+		flags |= stateChecksum
+	}
+	if s.minRun > 0 {
+		flags |= stateConstantRuns
+	}
+	data = append(data, flags, byte(s.outOfOrderPolicy))
+	for _, v := range []uint64{
+		s.firstTime, s.prevTimeWrite, s.count, s.lastValueWrite,
+		s.rebaselineGap, s.defaultDelta, uint64(s.resolution),
+		math.Float64bits(s.valueMultiplier), math.Float64bits(s.valueOffset),
+		math.Float64bits(s.exceptionBand), s.exceptionMaxInterval,
+		s.heldTime, math.Float64bits(s.heldValue),
+		s.lastInput, uint64(s.sortWindow),
+		s.minRun, s.runLen, s.runStep,
+		s.restartInterval, s.lastRestart,
+	} {
+		data = binary.BigEndian.AppendUint64(data, v)
+	}
+	data = binary.AppendUvarint(data, uint64(len(s.pending)))
+	for _, p := range s.pending {
+		data = binary.BigEndian.AppendUint64(data, p.Timestamp)
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(p.Value))
+	}
+	data = binary.AppendUvarint(data, uint64(len(s.restarts)))
+	for _, r := range s.restarts {
+		data = binary.BigEndian.AppendUint64(data, r.timestamp)
+		data = binary.BigEndian.AppendUint64(data, r.offset)
+		data = binary.BigEndian.AppendUint64(data, r.index)
+	}
+
+	for _, c := range []codecState{s.timeWriter, s.valueWriter} {
+		var state []byte
+		if c != nil {
+			var err error
+			if state, err = c.MarshalBinary(); err != nil {
+				return nil, err
+			}
+		}
+		data = binary.AppendUvarint(data, uint64(len(state)))
+		data = append(data, state...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores a Series from MarshalBinary output, positioned
+// at the first point for reading. The codecs it names must be registered.
+// data is copied.
+func (s *Series) UnmarshalBinary(data []byte) error {
+	r := stateReader{data: data}
+	if r.byte() != SERIES_STATE_VERSION {
+		return errSeriesState
+	}
+	res := Series{timeCodecID: r.byte(), valueCodecID: r.byte()}
+	numBits := r.uint64()
+	stream := r.bytes((numBits + 7) / 8)
+	flags := r.byte()
+	res.outOfOrderPolicy = int(r.byte())
+	res.firstTime = r.uint64()
+	res.prevTimeWrite = r.uint64()
+	res.count = r.uint64()
+	res.lastValueWrite = r.uint64()
+	res.rebaselineGap = r.uint64()
+	res.defaultDelta = r.uint64()
+	res.resolution = time.Duration(r.uint64())
+	res.valueMultiplier = math.Float64frombits(r.uint64())
+	res.valueOffset = math.Float64frombits(r.uint64())
+	res.exceptionBand = math.Float64frombits(r.uint64())
+	res.exceptionMaxInterval = r.uint64()
+	res.heldTime = r.uint64()
+	res.heldValue = math.Float64frombits(r.uint64())
+	res.lastInput = r.uint64()
+	res.sortWindow = int(r.uint64())
+	res.minRun = r.uint64()
+	res.runLen = r.uint64()
+	res.runStep = r.uint64()
+	res.restartInterval = r.uint64()
+	res.lastRestart = r.uint64()
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		res.pending = append(res.pending, Point{r.uint64(), math.Float64frombits(r.uint64())})
+	}
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		res.restarts = append(res.restarts, restartPoint{r.uint64(), r.uint64(), r.uint64()})
+	}
+	timeState := r.bytes(r.uvarint())
+	valueState := r.bytes(r.uvarint())
+	if r.err != nil || len(r.data) != 0 || flags >= stateConstantRuns<<1 || (flags&stateConstantRuns != 0) != (res.minRun > 0) {
+		return errSeriesState
+	}
+	if !res.validState(numBits) {
+		return errSeriesState
+	}
+	res.hasValueScale = flags&stateValueScale != 0
+	res.scaleOnRead = flags&stateScaleOnRead != 0
+	res.exceptionEnabled = flags&stateException != 0
+	res.hasHeld = flags&stateHeld != 0
+	res.hasInput = flags&stateInput != 0
+	res.checksum = flags&stateChecksum != 0
+
+	bs, err := bitUtil.FromBytes(append([]byte(nil), stream...), numBits)
+	if err != nil {
+		return err
+	}
+	res.Bs = *bs
+
+	timeCodec, ok := timeCodecs.lookup(res.timeCodecID)
+	if !ok {
+		return errSeriesState
+	}
+	valueCodec, ok := valueCodecs.lookup(res.valueCodecID)
+	if !ok {
+		return errSeriesState
+	}
+	// the writers are created lazily, so they only exist once a point was
+	// appended
+	if len(timeState) > 0 {
+		if err := timeCodec.UnmarshalBinary(timeState); err != nil {
+			return err
+		}
+		res.timeWriter = timeCodec
+	}
+	if len(valueState) > 0 {
+		if err := valueCodec.UnmarshalBinary(valueState); err != nil {
+			return err
+		}
+		res.valueWriter = valueCodec
+	}
+
+	res.Transforms = s.Transforms
+	*s = res
+	return nil
+}
+
+// validState reports whether the counts restored by UnmarshalBinary agree
+// with a stream of numBits bits and with each other, so later reads and
+// allocations can trust them.
+func (s *Series) validState(numBits uint64) bool {
+	// every point outside a constant run takes at least one bit
+	if s.minRun == 0 && (s.count > numBits || s.runLen > 0) {
+		return false
+	}
+	if s.outOfOrderPolicy > OUT_OF_ORDER_SORT || s.sortWindow < 0 {
+		return false
+	}
+	if len(s.pending) > 0 && (s.outOfOrderPolicy != OUT_OF_ORDER_SORT || len(s.pending) > s.sortWindow) {
+		return false
+	}
+	for _, r := range s.restarts {
+		if r.offset > numBits || r.index > s.count {
+			return false
+		}
+	}
+	return true
+}
+
+// stateReader reads the fields written by MarshalBinary, remembering the
+// first error so they can be read without checking each one.
+type stateReader struct {
+	data []byte
+	err  error
+}
+
+func (r *stateReader) bytes(n uint64) []byte {
+	if r.err != nil || n > uint64(len(r.data)) {
+		r.err = errSeriesState
+		return nil
+	}
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res
+}
+
+func (r *stateReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *stateReader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *stateReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errSeriesState
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *stateReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errSeriesState
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
@@ -0,0 +1,70 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func marshalSeries(opts ...Option) *Series {
+	s := NewSeries(opts...)
+	for i := 0; i < 1000; i++ {
+		s.AppendChecked(1440583200+60*uint64(i), float64(i%10))
+	}
+	return s
+}
+
+func TestUnmarshalBinary(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithRestartInterval(100), WithOutOfOrder(OUT_OF_ORDER_SORT, 4)}} {
+		s := marshalSeries(opts...)
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res Series
+		if err := res.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		res.Flush()
+		s.Flush()
+		want, _, _ := s.ReadAppend(nil, nil)
+		got, _, err := res.ReadAppend(nil, nil)
+		if err != nil || len(got) != len(want) {
+			t.Fatal(len(got), len(want), err)
+		}
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		corrupt func(s *Series)
+	}{
+		{"count past the stream", nil, func(s *Series) { s.count = 1 << 62 }},
+		{"run without constant runs", nil, func(s *Series) { s.runLen = 3 }},
+		{"restart past the stream", []Option{WithRestartInterval(100)}, func(s *Series) { s.restarts[2].offset = s.Bs.NumBits + 1 }},
+		{"restart past the count", []Option{WithRestartInterval(100)}, func(s *Series) { s.restarts[2].index = s.count + 1 }},
+		{"pending without sort", nil, func(s *Series) { s.pending = []Point{{1, 1}} }},
+		{"pending past the window", []Option{WithOutOfOrder(OUT_OF_ORDER_SORT, 1)}, func(s *Series) { s.pending = make([]Point, 2) }},
+	}
+	for _, test := range tests {
+		s := marshalSeries(test.opts...)
+		test.corrupt(s)
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res Series
+		if err := res.UnmarshalBinary(data); err != errSeriesState {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestDecodeAllCorruptCount(t *testing.T) {
+	s := marshalSeries()
+	s.count = 1 << 62
+	// the capacity is bounded by the stream instead of the count
+	if timestamps, _, err := s.DecodeAll(); len(timestamps) > 1000 || cap(timestamps) > int(s.Bs.NumBits) {
+		t.Fatal(len(timestamps), cap(timestamps), err)
+	}
+}
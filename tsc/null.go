@@ -0,0 +1,45 @@
+package tsc
+
+import (
+	"math"
+)
+
+// NULL_VALUE_BITS is the value stored by AppendNull: a quiet NaN whose
+// payload spells "null", unlike the NaN of math.NaN or arithmetic.
+const NULL_VALUE_BITS = 0x7ff86e756c6c0000
+
+// AppendNull records that the sample at timestamp is missing, as a point
+// whose value is NULL_VALUE_BITS, and returns the number of bits added. It
+// bypasses Transforms and ends a report-by-exception hold. With
+// OUT_OF_ORDER_SORT it goes through the sort window like AppendChecked, and
+// is dropped if it arrives too late to be stored in order. Every read path
+// returns the point, and IsNull tells it apart from a real NaN.
+func (s *Series) AppendNull(timestamp uint64) uint64 {
+	before := s.Bs.NumBits
+	if s.outOfOrderPolicy != OUT_OF_ORDER_SORT {
+		s.appendNull(timestamp)
+	} else if !s.hasInput || timestamp >= s.lastInput {
+		s.addPending(Point{timestamp, math.Float64frombits(NULL_VALUE_BITS)})
+	}
+	return s.Bs.NumBits - before
+}
+
+func (s *Series) appendNull(timestamp uint64) {
+	s.lastInput, s.hasInput = timestamp, true
+	if s.hasHeld {
+		s.appendPoint(s.heldTime, s.heldValue)
+		s.hasHeld = false
+	}
+	s.appendPoint(timestamp, math.Float64frombits(NULL_VALUE_BITS))
+}
+
+// AppendNull records a missing sample, see Series.AppendNull.
+func (e *Encoder) AppendNull(timestamp uint64) uint64 {
+	return e.s.AppendNull(timestamp)
+}
+
+// IsNull reports whether value marks a missing sample written by
+// AppendNull.
+func IsNull(value float64) bool {
+	return math.Float64bits(value) == NULL_VALUE_BITS
+}
@@ -0,0 +1,56 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestAppendNullSortWindow(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries(WithOutOfOrder(OUT_OF_ORDER_SORT, 2))
+	s.AppendChecked(t0, 1)
+	s.AppendChecked(t0+120, 3)
+	s.AppendNull(t0 + 60)
+	s.AppendChecked(t0+180, 4)
+	s.AppendChecked(t0+240, 5)
+	// t0 has left the window, so this one is too late
+	if bits := s.AppendNull(t0 + 30); bits != 0 {
+		t.Fatal("stored a null out of order")
+	}
+	s.Flush()
+
+	timestamps, values, err := s.ReadAppend(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint64{t0, t0 + 60, t0 + 120, t0 + 180, t0 + 240}
+	if len(timestamps) != len(want) {
+		t.Fatal(timestamps)
+	}
+	for i := range want {
+		if timestamps[i] != want[i] || IsNull(values[i]) != (i == 1) {
+			t.Fatalf("point %d is (%d, %v), want (%d, null %v)", i, timestamps[i], values[i], want[i], i == 1)
+		}
+	}
+}
+
+func TestAppendNullReportByException(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries()
+	s.SetReportByException(1, 3600)
+	s.Append(t0, 5)
+	s.Append(t0+60, 5.5)
+	s.AppendNull(t0 + 120)
+	s.Append(t0+180, 5)
+	s.Flush()
+
+	timestamps, values, err := s.ReadAppend(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the held 5.5 ends its level before the null; 5 is stored as the
+	// null is not a value to compare with
+	if len(timestamps) != 4 || timestamps[1] != t0+60 || values[1] != 5.5 ||
+		!IsNull(values[2]) || timestamps[3] != t0+180 {
+		t.Fatal(timestamps, values)
+	}
+}
@@ -0,0 +1,80 @@
+package tsc
+
+import (
+	"time"
+)
+
+// Option configures a Series created by NewSeries.
+type Option func(*Series)
+
+// NewSeries returns an empty Series configured by opts. The zero Series is
+// equivalent to NewSeries().
+func NewSeries(opts ...Option) *Series {
+	s := &Series{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithRebaselineGap makes a timestamp more than gap away from the previous
+// one be stored in full, with the delta reset to the default. Only the
+// codecs with a 64-bit escape, TIME_CODEC_DOD_ESCAPE, TIME_CODEC_DOD64 and
+// TIME_CODEC_AUTO, can rebaseline, none does by default. TIME_CODEC_AUTO
+// also picks sparse encoding above gap instead of AUTO_SPARSE_GAP.
+func WithRebaselineGap(gap uint64) Option {
+	return func(s *Series) {
+		s.rebaselineGap = gap
+	}
+}
+
+// WithDefaultDelta sets the delta assumed between the first two timestamps
+// (DEFAULT_DELTA by default, or one minute in the units of WithResolution).
+// Setting it to the usual sampling interval saves a few bits per series and
+// after every rebaseline.
+func WithDefaultDelta(delta uint64) Option {
+	return func(s *Series) {
+		s.defaultDelta = delta
+	}
+}
+
+// WithResolution sets the unit of the timestamps, so DEFAULT_DELTA and
+// AUTO_SPARSE_GAP, which are given in seconds, are converted to it. A
+// resolution finer than a second also selects TIME_CODEC_DOD64, as such
+// epochs do not fit in 32 bits; pass WithTimeCodec afterwards to override.
+func WithResolution(resolution time.Duration) Option {
+	return func(s *Series) {
+		s.resolution = resolution
+		if resolution < time.Second && s.timeCodecID == TIME_CODEC_DOD {
+			s.timeCodecID = TIME_CODEC_DOD64
+		}
+	}
+}
+
+// timeDefaults returns the default delta, or 0 to leave it to the codec,
+// and the gap above which TIME_CODEC_AUTO picks sparse encoding, in the
+// units of the timestamps.
+func (s *Series) timeDefaults() (delta, sparseGap uint64) {
+	delta, sparseGap = s.defaultDelta, s.rebaselineGap
+	if sparseGap == 0 {
+		sparseGap = AUTO_SPARSE_GAP
+	}
+	if s.resolution > 0 {
+		if delta == 0 {
+			delta = uint64(DEFAULT_DELTA * time.Second / s.resolution)
+		}
+		if s.rebaselineGap == 0 {
+			sparseGap = uint64(AUTO_SPARSE_GAP * time.Second / s.resolution)
+		}
+	}
+	return delta, sparseGap
+}
+
+// WithStrictDecode makes Read check that timestamps never go backwards and
+// prefix every error with the index and bit offset of the point being
+// read, so corruption is reported where it starts.
+func WithStrictDecode() Option {
+	return func(s *Series) {
+		s.strict = true
+	}
+}
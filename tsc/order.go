@@ -0,0 +1,108 @@
+package tsc
+
+import (
+	"errors"
+	"slices"
+	"sort"
+)
+
+// policies for points passed to AppendChecked out of order
+const (
+	OUT_OF_ORDER_ERROR = iota
+	OUT_OF_ORDER_DROP
+	OUT_OF_ORDER_SORT
+)
+
+var (
+	ErrOutOfOrder     = errors.New("Timestamp is older than the previous point")
+	ErrTimestampRange = errors.New("Timestamp is out of the range of the time codec")
+)
+
+// WithOutOfOrder sets what AppendChecked does with a point older than the
+// previous one (OUT_OF_ORDER_ERROR by default). With OUT_OF_ORDER_SORT, up
+// to window points are held back and appended in timestamp order, so a
+// point can arrive up to window points late; one arriving later still fails
+// with ErrOutOfOrder.
+func WithOutOfOrder(policy int, window int) Option {
+	return func(s *Series) {
+		s.outOfOrderPolicy = policy
+		s.sortWindow = window
+	}
+}
+
+// AppendChecked is Append for timestamps that may go backwards. A point
+// older than the previous one is handled by the WithOutOfOrder policy
+// instead of being stored as is, which ReadRange, Seek and
+// WithStrictDecode do not expect. A point the time codec would wrap around
+// fails with ErrTimestampRange, such as a first timestamp beyond 32 bits or
+// a jump beyond 2^31 with TIME_CODEC_DOD. Points held back by
+// OUT_OF_ORDER_SORT are appended by Flush.
+func (s *Series) AppendChecked(timestamp uint64, value float64) error {
+	if s.hasInput && timestamp < s.lastInput {
+		if s.outOfOrderPolicy == OUT_OF_ORDER_DROP {
+			return nil
+		}
+		return ErrOutOfOrder
+	}
+	if !s.inReach(timestamp) {
+		return ErrTimestampRange
+	}
+	if s.outOfOrderPolicy != OUT_OF_ORDER_SORT {
+		s.Append(timestamp, value)
+		return nil
+	}
+	s.addPending(Point{timestamp, value})
+	return nil
+}
+
+// inReach reports whether the time codec can store timestamp after the
+// points appended or held back so far. The last point stored and those
+// held back by the sort window must fit within one jump together, so any
+// two of them stored next to each other do.
+func (s *Series) inReach(timestamp uint64) bool {
+	r, ok := s.timeEncoder().(timeReach)
+	if !ok {
+		return true
+	}
+	maxFirst, maxJump := r.reach()
+	lo, hi := timestamp, timestamp
+	if len(s.pending) > 0 {
+		lo = min(lo, s.pending[0].Timestamp)
+		hi = max(hi, s.pending[len(s.pending)-1].Timestamp)
+	}
+	if s.count == 0 {
+		return lo <= maxFirst && hi-lo <= maxJump
+	}
+	return hi-min(lo, s.prevTimeWrite) <= maxJump
+}
+
+// addPending holds p back in the sort window, appending the oldest point
+// once the window is full.
+func (s *Series) addPending(p Point) {
+	i := sort.Search(len(s.pending), func(i int) bool { return s.pending[i].Timestamp > p.Timestamp })
+	s.pending = slices.Insert(s.pending, i, p)
+	if len(s.pending) > s.sortWindow {
+		p := s.pending[0]
+		s.pending = append(s.pending[:0], s.pending[1:]...)
+		s.appendPending(p)
+	}
+}
+
+// flushPending appends the points held back by OUT_OF_ORDER_SORT.
+func (s *Series) flushPending() {
+	pending := s.pending
+	s.pending = s.pending[:0]
+	for _, p := range pending {
+		s.appendPending(p)
+	}
+}
+
+// appendPending appends a point leaving the sort window, through
+// AppendNull if it was held back by it.
+func (s *Series) appendPending(p Point) {
+	if IsNull(p.Value) {
+		s.appendNull(p.Timestamp)
+	} else {
+		s.Append(p.Timestamp, p.Value)
+	}
+}
@@ -0,0 +1,55 @@
+package tsc
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+func TestAppendCheckedRange(t *testing.T) {
+	const t0 = 1440583200
+	tests := []struct {
+		name       string
+		opts       []Option
+		timestamps []uint64
+		// index of the timestamp that fails, -1 if none
+		fails int
+	}{
+		{"dod first", nil, []uint64{1 << 32}, 0},
+		{"dod largest jump", nil, []uint64{t0, t0 + 1<<31, t0 + 1<<32}, -1},
+		{"dod jump", nil, []uint64{t0, t0 + 60, t0 + 60 + 1<<31 + 1}, 2},
+		{"escape first", []Option{WithTimeCodec(TIME_CODEC_DOD_ESCAPE)}, []uint64{1 << 32}, 0},
+		{"escape jump", []Option{WithTimeCodec(TIME_CODEC_DOD_ESCAPE)}, []uint64{t0, t0 + 1<<40}, -1},
+		{"dod64", []Option{WithTimeCodec(TIME_CODEC_DOD64)}, []uint64{1 << 40, 1<<40 + 1<<50}, -1},
+		// the held back points must fit within one jump of each other
+		{"sort window", []Option{WithOutOfOrder(OUT_OF_ORDER_SORT, 4)}, []uint64{t0 + 1<<31, t0, t0 + 1<<31 + 1}, 2},
+	}
+	for _, test := range tests {
+		s := NewSeries(test.opts...)
+		var points []Point
+		for i, timestamp := range test.timestamps {
+			err := s.AppendChecked(timestamp, float64(i))
+			if i == test.fails {
+				if err != ErrTimestampRange {
+					t.Fatalf("%s: point %d: %v", test.name, i, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: point %d: %v", test.name, i, err)
+			}
+			points = append(points, Point{timestamp, float64(i)})
+		}
+		s.Flush()
+		slices.SortStableFunc(points, func(a, b Point) int { return cmp.Compare(a.Timestamp, b.Timestamp) })
+		timestamps, values, err := s.ReadAppend(nil, nil)
+		if err != nil || len(timestamps) != len(points) {
+			t.Fatalf("%s: %v %v", test.name, timestamps, err)
+		}
+		for i, p := range points {
+			if timestamps[i] != p.Timestamp || values[i] != p.Value {
+				t.Fatalf("%s: point %d is (%d, %v), want %v", test.name, i, timestamps[i], values[i], p)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package tsc
+
+import (
+	"runtime"
+	"sync"
+)
+
+type decodeBuffer struct {
+	timestamps []uint64
+	values     []float64
+}
+
+var decodeBufferPool = sync.Pool{
+	New: func() interface{} { return new(decodeBuffer) },
+}
+
+// DecodeBlocks decodes the points of every block concurrently, using at
+// most parallelism goroutines (GOMAXPROCS if parallelism <= 0), and appends
+// them to timestamps and values in block order. Each block is read from a
+// Snapshot, so the read positions of blocks are left alone and a Series
+// may appear more than once.
+func DecodeBlocks(timestamps []uint64, values []float64, blocks []*Series, parallelism int) ([]uint64, []float64, error) {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	buffers := make([]*decodeBuffer, len(blocks))
+	errs := make([]error, len(blocks))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < parallelism && w < len(blocks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				buf := decodeBufferPool.Get().(*decodeBuffer)
+				buf.timestamps, buf.values, errs[i] = blocks[i].Snapshot().ReadAppend(buf.timestamps[:0], buf.values[:0])
+				buffers[i] = buf
+			}
+		}()
+	}
+	for i := range blocks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var err error
+	for i, buf := range buffers {
+		if err == nil {
+			err = errs[i]
+		}
+		if err == nil {
+			timestamps = append(timestamps, buf.timestamps...)
+			values = append(values, buf.values...)
+		}
+		decodeBufferPool.Put(buf)
+	}
+	return timestamps, values, err
+}
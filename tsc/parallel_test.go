@@ -0,0 +1,90 @@
+package tsc
+
+import (
+	"testing"
+)
+
+// parallelBlocks returns n blocks of different lengths.
+func parallelBlocks(n int) []*Series {
+	var blocks []*Series
+	for b := 0; b < n; b++ {
+		s := &Series{}
+		for i := 0; i < 100*b+1; i++ {
+			s.Append(1440583200+uint64(7200*b+60*i), float64(b*1000+i%13))
+		}
+		blocks = append(blocks, s)
+	}
+	return blocks
+}
+
+// decodeSequential reads the blocks one after the other, moving their read
+// positions.
+func decodeSequential(blocks []*Series) ([]uint64, []float64, error) {
+	var timestamps []uint64
+	var values []float64
+	for _, s := range blocks {
+		s.resetRead()
+		var err error
+		if timestamps, values, err = s.ReadAppend(timestamps, values); err != nil {
+			return timestamps, values, err
+		}
+	}
+	return timestamps, values, nil
+}
+
+func TestDecodeBlocks(t *testing.T) {
+	blocks := parallelBlocks(10)
+	// the same Series twice
+	blocks = append(blocks, blocks[4])
+	for _, parallelism := range []int{-1, 0, 1, 3, 64} {
+		// a block partly read by the caller is still decoded in full
+		blocks[2].Read()
+		timestamps, values, err := DecodeBlocks([]uint64{1}, []float64{1}, blocks, parallelism)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timestamps[0] != 1 || values[0] != 1 {
+			t.Fatal("DecodeBlocks overwrote the slices it appends to")
+		}
+		if timestamp, _, err := blocks[2].Read(); err != nil || timestamp != 1440583200+7200*2+60 {
+			t.Fatal("DecodeBlocks moved the read position", timestamp, err)
+		}
+		want, wantValues, err := decodeSequential(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		timestamps, values = timestamps[1:], values[1:]
+		if len(timestamps) != len(want) || len(values) != len(want) {
+			t.Fatalf("parallelism %d: %d points, want %d", parallelism, len(timestamps), len(want))
+		}
+		for i := range want {
+			if timestamps[i] != want[i] || values[i] != wantValues[i] {
+				t.Fatalf("parallelism %d: point %d is (%d, %v), want (%d, %v)",
+					parallelism, i, timestamps[i], values[i], want[i], wantValues[i])
+			}
+		}
+		for _, s := range blocks {
+			s.resetRead()
+		}
+	}
+}
+
+func TestDecodeBlocksError(t *testing.T) {
+	blocks := parallelBlocks(6)
+	// cut the stream of block 3 in the middle of a point
+	blocks[3].Bs.NumBits -= 3
+	_, _, wantErr := decodeSequential(blocks)
+	if wantErr == nil {
+		t.Fatal("truncated block decodes")
+	}
+	for _, parallelism := range []int{0, 1, 4} {
+		timestamps, _, err := DecodeBlocks(nil, nil, blocks, parallelism)
+		if err == nil || err.Error() != wantErr.Error() {
+			t.Fatalf("parallelism %d: %v, want %v", parallelism, err, wantErr)
+		}
+		// only the blocks before the broken one are appended
+		if len(timestamps) != 1+101+201 {
+			t.Fatalf("parallelism %d: %d points", parallelism, len(timestamps))
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/huangaz/tsc/bitUtil"
+	"math"
+)
+
+const (
+	PROMETHEUS_HEADER_BYTES = 2
+	PROMETHEUS_MAX_SAMPLES  = math.MaxUint16
+	PROMETHEUS_LEADING_BITS = 5
+	PROMETHEUS_BLOCK_BITS   = 6
+	PROMETHEUS_MAX_LEADING  = (1 << PROMETHEUS_LEADING_BITS) - 1
+)
+
+var (
+	errPrometheusChunk   = errors.New("Invalid Prometheus chunk")
+	errPrometheusOptions = errors.New("Series options are not compatible with Prometheus chunks")
+	errPrometheusSamples = errors.New("Too many points for a Prometheus chunk")
+)
+
+/*
+* The XOR chunk of the Prometheus TSDB (tsdb/chunkenc): a big endian sample
+* count followed by the bit stream. Timestamps are int64 milliseconds.
+*
+* sample	timestamp			value
+* first		varint				64 bits
+* second	uvarint delta			xor
+* others	delta of delta, see below	xor
+*
+* deltaOfDelta		tag	value bits
+* 0			0	-
+* -8191,8192		10	14
+* -65535,65536		110	17
+* -524287,524288	1110	20
+* others		1111	64
+*
+* The xor is the Gorilla one with 5 bits of leading zeros and 6 of block
+* size, 64 stored as 0.
+ */
+var prometheusDodBits = []uint64{14, 17, 20, 64}
+
+// WithPrometheusXOR selects the timestamp and value encodings of the
+// Prometheus XOR chunk, see Encoder.PrometheusChunk. It cannot be combined
+// with options that add to the stream, such as WithChecksum.
+func WithPrometheusXOR() Option {
+	return func(s *Series) {
+		s.timeCodecID = TIME_CODEC_PROMETHEUS
+		s.valueCodecID = VALUE_CODEC_PROMETHEUS
+	}
+}
+
+// PrometheusChunk returns the points appended so far as a Prometheus XOR
+// chunk. The Encoder must have been created with WithPrometheusXOR and
+// hold at most PROMETHEUS_MAX_SAMPLES points. Points held back, see Flush,
+// are not included.
+func (e *Encoder) PrometheusChunk() ([]byte, error) {
+	if !e.s.prometheusCompatible() {
+		return nil, errPrometheusOptions
+	}
+	if e.s.count > PROMETHEUS_MAX_SAMPLES {
+		return nil, errPrometheusSamples
+	}
+	chunk := make([]byte, PROMETHEUS_HEADER_BYTES, PROMETHEUS_HEADER_BYTES+e.s.Bs.Len())
+	binary.BigEndian.PutUint16(chunk, uint16(e.s.count))
+	return append(chunk, e.s.Bs.Bytes()...), nil
+}
+
+// NewPrometheusDecoder returns a Decoder over the samples of a Prometheus
+// XOR chunk. The chunk does not record where its last sample ends, so the
+// samples are decoded once to find it. chunk is not copied and must not be
+// modified while the Decoder is in use.
+func NewPrometheusDecoder(chunk []byte) (*Decoder, error) {
+	if len(chunk) < PROMETHEUS_HEADER_BYTES {
+		return nil, errPrometheusChunk
+	}
+	n := binary.BigEndian.Uint16(chunk)
+	data := chunk[PROMETHEUS_HEADER_BYTES:]
+	d, err := NewDecoder(data, uint64(len(data))*8, WithPrometheusXOR())
+	if err != nil {
+		return nil, err
+	}
+	for i := uint16(0); i < n; i++ {
+		if _, _, err := d.Read(); err != nil {
+			return nil, err
+		}
+	}
+	return NewDecoder(data, d.s.Bs.BitPos, WithPrometheusXOR())
+}
+
+func (s *Series) prometheusCompatible() bool {
+	return s.timeCodecID == TIME_CODEC_PROMETHEUS && s.valueCodecID == VALUE_CODEC_PROMETHEUS &&
+		!s.checksum && s.minRun == 0 && s.restartInterval == 0
+}
+
+// prometheusTimeCodec is the timestamp encoding of Prometheus XOR chunks.
+type prometheusTimeCodec struct {
+	// 0, 1 or 2 for any later sample
+	sample    uint8
+	prevTime  uint64
+	prevDelta uint64
+}
+
+func (c *prometheusTimeCodec) ID() uint8 {
+	return TIME_CODEC_PROMETHEUS
+}
+
+func (c *prometheusTimeCodec) Append(bs *bitUtil.BitStream, timestamp uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	delta := timestamp - c.prevTime
+	switch c.sample {
+	case 0:
+		appendBytes(bs, binary.AppendVarint(buf[:0], int64(timestamp)))
+		c.sample++
+	case 1:
+		appendBytes(bs, binary.AppendUvarint(buf[:0], delta))
+		c.sample++
+	default:
+		dod := int64(delta - c.prevDelta)
+		if dod == 0 {
+			bs.AddValueToBitStream(0, 1)
+			break
+		}
+		for n, bits := range prometheusDodBits {
+			if bits == 64 {
+				bs.AddValueToBitStream(1<<(n+1)-1, uint64(n+1))
+			} else if -(1<<(bits-1))+1 <= dod && dod <= 1<<(bits-1) {
+				// n ones and a zero after the first one
+				bs.AddValueToBitStream(1<<(n+2)-2, uint64(n+2))
+			} else {
+				continue
+			}
+			bs.AddValueToBitStream(uint64(dod)&(1<<bits-1), bits)
+			break
+		}
+	}
+	c.prevDelta = delta
+	c.prevTime = timestamp
+}
+
+func (c *prometheusTimeCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	switch c.sample {
+	case 0:
+		v, err := binary.ReadVarint(byteReader{bs})
+		if err != nil {
+			return 0, err
+		}
+		c.sample++
+		c.prevTime = uint64(v)
+		return c.prevTime, nil
+	case 1:
+		delta, err := binary.ReadUvarint(byteReader{bs})
+		if err != nil {
+			return 0, err
+		}
+		c.sample++
+		c.prevDelta = delta
+		c.prevTime += delta
+		return c.prevTime, nil
+	}
+	n, err := bs.ReadUnary(uint64(len(prometheusDodBits)))
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		bits := prometheusDodBits[n-1]
+		dod, err := bs.ReadValueFromBitStream(bits)
+		if err != nil {
+			return 0, err
+		}
+		if bits != 64 && dod > 1<<(bits-1) {
+			// wraps around to the negative value
+			dod -= 1 << bits
+		}
+		c.prevDelta += dod
+	}
+	c.prevTime += c.prevDelta
+	return c.prevTime, nil
+}
+
+func (c *prometheusTimeCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 17)
+	data[0] = c.sample
+	data = binary.BigEndian.AppendUint64(data, c.prevTime)
+	data = binary.BigEndian.AppendUint64(data, c.prevDelta)
+	return data, nil
+}
+
+func (c *prometheusTimeCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 17 || data[0] > 2 {
+		return errCodecState
+	}
+	c.sample = data[0]
+	c.prevTime = binary.BigEndian.Uint64(data[1:])
+	c.prevDelta = binary.BigEndian.Uint64(data[9:])
+	return nil
+}
+
+func (c *prometheusTimeCodec) clone() TimeCodec {
+	res := *c
+	return &res
+}
+
+// prometheusValueCodec is the value encoding of Prometheus XOR chunks. It
+// differs from xorCodec in storing the first value as is, in the width of
+// the block size and in when the previous block is reused.
+type prometheusValueCodec struct {
+	started  bool
+	hasBlock bool
+	prev     uint64
+	leading  uint64
+	trailing uint64
+}
+
+func (c *prometheusValueCodec) ID() uint8 {
+	return VALUE_CODEC_PROMETHEUS
+}
+
+func (c *prometheusValueCodec) Append(bs *bitUtil.BitStream, value uint64) {
+	xor := value ^ c.prev
+	c.prev = value
+	if !c.started {
+		bs.AddValueToBitStream(value, 64)
+		c.started = true
+		return
+	}
+	if xor == 0 {
+		bs.AddValueToBitStream(0, 1)
+		return
+	}
+	bs.AddValueToBitStream(1, 1)
+
+	leading := bitUtil.Clz(xor)
+	trailing := bitUtil.Ctz(xor)
+	if leading > PROMETHEUS_MAX_LEADING {
+		leading = PROMETHEUS_MAX_LEADING
+	}
+	if c.hasBlock && leading >= c.leading && trailing >= c.trailing {
+		bs.AddValueToBitStream(0, 1)
+		bs.AddValueToBitStream(xor>>c.trailing, 64-c.leading-c.trailing)
+		return
+	}
+	c.hasBlock, c.leading, c.trailing = true, leading, trailing
+	blockSize := 64 - leading - trailing
+	bs.AddValueToBitStream(1, 1)
+	bs.AddValueToBitStream(leading, PROMETHEUS_LEADING_BITS)
+	// 64 does not fit and 0 never occurs, so 0 stands for 64
+	bs.AddValueToBitStream(blockSize&(1<<PROMETHEUS_BLOCK_BITS-1), PROMETHEUS_BLOCK_BITS)
+	bs.AddValueToBitStream(xor>>trailing, blockSize)
+}
+
+func (c *prometheusValueCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	if !c.started {
+		value, err := bs.ReadValueFromBitStream(64)
+		if err != nil {
+			return 0, err
+		}
+		c.started = true
+		c.prev = value
+		return value, nil
+	}
+	nonZero, err := bs.ReadValueFromBitStream(1)
+	if err != nil || nonZero == 0 {
+		return c.prev, err
+	}
+	newBlock, err := bs.ReadValueFromBitStream(1)
+	if err != nil {
+		return 0, err
+	}
+	if newBlock == 1 {
+		leading, err := bs.ReadValueFromBitStream(PROMETHEUS_LEADING_BITS)
+		if err != nil {
+			return 0, err
+		}
+		blockSize, err := bs.ReadValueFromBitStream(PROMETHEUS_BLOCK_BITS)
+		if err != nil {
+			return 0, err
+		}
+		if blockSize == 0 {
+			blockSize = 64
+		}
+		if leading+blockSize > 64 {
+			return 0, errXorBlock
+		}
+		c.hasBlock, c.leading, c.trailing = true, leading, 64-leading-blockSize
+	} else if !c.hasBlock {
+		return 0, errXorBlock
+	}
+	xor, err := bs.ReadValueFromBitStream(64 - c.leading - c.trailing)
+	if err != nil {
+		return 0, err
+	}
+	c.prev ^= xor << c.trailing
+	return c.prev, nil
+}
+
+func (c *prometheusValueCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 2, 26)
+	if c.started {
+		data[0] = 1
+	}
+	if c.hasBlock {
+		data[1] = 1
+	}
+	data = binary.BigEndian.AppendUint64(data, c.prev)
+	data = binary.BigEndian.AppendUint64(data, c.leading)
+	data = binary.BigEndian.AppendUint64(data, c.trailing)
+	return data, nil
+}
+
+func (c *prometheusValueCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 26 || data[0] > 1 || data[1] > 1 {
+		return errCodecState
+	}
+	c.started = data[0] == 1
+	c.hasBlock = data[1] == 1
+	c.prev = binary.BigEndian.Uint64(data[2:])
+	c.leading = binary.BigEndian.Uint64(data[10:])
+	c.trailing = binary.BigEndian.Uint64(data[18:])
+	if c.leading+c.trailing > 64 {
+		return errCodecState
+	}
+	return nil
+}
+
+func (c *prometheusValueCodec) clone() ValueCodec {
+	res := *c
+	return &res
+}
+
+func appendBytes(bs *bitUtil.BitStream, data []byte) {
+	for _, b := range data {
+		bs.AddValueToBitStream(uint64(b), 8)
+	}
+}
+
+// byteReader reads whole bytes from the bit position of a stream, for the
+// varints of Prometheus chunks.
+type byteReader struct {
+	bs *bitUtil.BitStream
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	b, err := r.bs.ReadValueFromBitStream(8)
+	return byte(b), err
+}
@@ -0,0 +1,78 @@
+package tsc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+// prometheusChunk was written by the XOR appender of Prometheus v0.54.1
+// (tsdb/chunkenc) from prometheusPoints. The deltas cover every delta of
+// delta bucket, the values every xor case.
+const prometheusChunk = "001680b49997ed533ff000000000000098750c26fff600057ffd80e800e000707d7c7fc8791000eb3d00061fffc94511a57c4362ef0005f975b87f0be3cd6308007001fff0000000000003bbfff7ff8000000000000680004ffe0000000000001de0001202c800000000000708000c0000200000000001ddffff000018000000000074000040000200000000001ffffffffffff00001002298000000000078000000000040000bfffffffffffdffffde00000004a81752d1002c8000000000007fffffffed5fa2b4c4009843f6a8885a31b8ad33ffc90fdaa22168c0"
+
+func prometheusPoints() []Point {
+	deltas := []uint64{15000, 15000, 15000, 15001, 14999, 15000, 23192, 15000, 23193, 15000,
+		80536, 15000, 80537, 15000, 539288, 15000, 539289, 15000, 10000000000, 15000, 1}
+	values := []float64{1, 1, 1, 2.5, 2.5, -3, 1e300, 1e-300, math.NaN(), math.Copysign(0, -1),
+		math.Inf(1), 0, 100, 100.25, 100.5, 100.75, 7, 7, 7, 42, math.Pi, 1}
+	points := make([]Point, len(values))
+	timestamp := uint64(1440583200000)
+	for i, v := range values {
+		if i > 0 {
+			timestamp += deltas[i-1]
+		}
+		points[i] = Point{timestamp, v}
+	}
+	return points
+}
+
+func TestPrometheusChunkUpstream(t *testing.T) {
+	want, err := hex.DecodeString(prometheusChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := prometheusPoints()
+
+	e := NewEncoder(WithPrometheusXOR())
+	for _, p := range points {
+		e.Append(p.Timestamp, p.Value)
+	}
+	chunk, err := e.PrometheusChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(chunk, want) {
+		t.Fatalf("chunk differs from upstream:\n%x\n%x", chunk, want)
+	}
+
+	d, err := NewPrometheusDecoder(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range points {
+		timestamp, value, err := d.Read()
+		if err != nil || timestamp != p.Timestamp || math.Float64bits(value) != math.Float64bits(p.Value) {
+			t.Fatalf("point %d: (%d, %v) %v, want (%d, %v)", i, timestamp, value, err, p.Timestamp, p.Value)
+		}
+	}
+	if !d.Done() {
+		t.Fatal("points left after the chunk's sample count")
+	}
+}
+
+func TestPrometheusChunkErrors(t *testing.T) {
+	if _, err := NewEncoder().PrometheusChunk(); err != errPrometheusOptions {
+		t.Fatal(err)
+	}
+	if _, err := NewPrometheusDecoder([]byte{0}); err != errPrometheusChunk {
+		t.Fatal(err)
+	}
+	// the sample count promises more than the stream holds
+	chunk, _ := hex.DecodeString(prometheusChunk)
+	chunk[0] = 1
+	if _, err := NewPrometheusDecoder(chunk); err == nil {
+		t.Fatal("decoded a sample past the end")
+	}
+}
@@ -0,0 +1,50 @@
+package tsc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	const t0 = 1440583200
+	s := NewSeries(WithDecimalValues())
+	s.Transforms = []Transform{Scale(2, 0)}
+	write := func() {
+		for i := 0; i < 5000; i++ {
+			s.Append(t0+60*uint64(i), float64(i%100)/4)
+		}
+	}
+	write()
+	want := s.Bs.Bytes()
+	s.Read()
+
+	first := &s.Bs.Pages[0][0]
+	s.Reset()
+	if s.count != 0 || s.Bs.NumBits != 0 {
+		t.Fatal("Reset kept points")
+	}
+	write()
+	reused := false
+	for _, page := range s.Bs.Pages {
+		reused = reused || &page[0] == first
+	}
+	if !reused {
+		t.Fatal("Reset did not keep the pages")
+	}
+	// the options and transforms are kept
+	if !bytes.Equal(s.Bs.Bytes(), want) {
+		t.Fatal("different stream after Reset")
+	}
+	if _, value, err := s.Read(); err != nil || value != 0 {
+		t.Fatal(value, err)
+	}
+	if _, value, err := s.Read(); err != nil || value != 0.5 {
+		t.Fatal("transform not kept:", value, err)
+	}
+
+	s.Release()
+	write()
+	if !bytes.Equal(s.Bs.Bytes(), want) {
+		t.Fatal("different stream after Release")
+	}
+}
@@ -0,0 +1,73 @@
+package tsc
+
+import (
+	"errors"
+	"sort"
+)
+
+var errStreamSeek = errors.New("Cannot seek in a stream decoder")
+
+// restartPoint is a point written with fresh codecs, where decoding can
+// start.
+type restartPoint struct {
+	timestamp uint64
+	offset    uint64
+	index     uint64
+}
+
+// WithRestartInterval makes Series start its codecs afresh every interval
+// points, storing the next timestamp and value in full, and remember where
+// in the stream each restart is, so Seek can jump to the one before a
+// timestamp instead of decoding from the first point. Each restart costs
+// a few dozen bits and it disables the batch path of AppendBatch. The
+// index of restarts is kept with the Series and by MarshalBinary, not in
+// the stream; streams written with it must be read with it. The timestamp
+// of a restart is stored like the first one, so with TIME_CODEC_DOD and
+// TIME_CODEC_DOD_ESCAPE it must fit in 32 bits.
+func WithRestartInterval(interval uint64) Option {
+	return func(s *Series) {
+		s.restartInterval = interval
+	}
+}
+
+// restartWrite starts fresh codecs if the next point is due a restart.
+func (s *Series) restartWrite(timestamp uint64) {
+	if s.count > 0 && s.count-s.lastRestart < s.restartInterval {
+		return
+	}
+	s.timeWriter, s.valueWriter = nil, nil
+	s.lastRestart = s.count
+	s.restarts = append(s.restarts, restartPoint{timestamp, s.Bs.NumBits, s.count})
+}
+
+// restartRead is restartWrite for the reading side.
+func (s *Series) restartRead() {
+	if s.restartInterval > 0 && s.readIndex-s.readRestart >= s.restartInterval {
+		s.timeReader, s.valueReader = nil, nil
+		s.readRestart = s.readIndex
+	}
+}
+
+// Seek moves the read position to the last restart at or before
+// timestamp, see WithRestartInterval, or to the first point if there is
+// none. Read then returns the points from there on, at most an interval of
+// them before timestamp, not counting the points of constant runs.
+func (s *Series) Seek(timestamp uint64) {
+	i := sort.Search(len(s.restarts), func(i int) bool { return s.restarts[i].timestamp > timestamp })
+	s.resetRead()
+	if i > 0 {
+		r := s.restarts[i-1]
+		s.Bs.BitPos = r.offset
+		s.readIndex, s.readRestart = r.index, r.index
+	}
+}
+
+// Seek is Series.Seek. Decoders from NewBlockDecoder have the restarts of
+// the block, those from NewStreamDecoder cannot seek at all.
+func (d *Decoder) Seek(timestamp uint64) error {
+	if d.src != nil {
+		return errStreamSeek
+	}
+	d.s.Seek(timestamp)
+	return nil
+}
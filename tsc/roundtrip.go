@@ -0,0 +1,57 @@
+package tsc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Point is a single sample of a series.
+type Point struct {
+	Timestamp uint64
+	Value     float64
+}
+
+// RoundTrip encodes points into a Series configured by opts, decodes them
+// again and returns an error describing the first difference, if any.
+// Values are compared bit for bit, so NaN payloads and -0 must survive too.
+// Points go through AppendChecked, so the WithOutOfOrder policy applies:
+// dropped points are not expected back, sorted ones are expected in order
+// and a rejected one fails with ErrOutOfOrder. Points held back are stored
+// with Flush before decoding.
+func RoundTrip(points []Point, opts ...Option) error {
+	s := NewSeries(opts...)
+	want := make([]Point, 0, len(points))
+	for i, p := range points {
+		if err := s.AppendChecked(p.Timestamp, p.Value); err != nil {
+			return fmt.Errorf("point %d of %d: %w", i, len(points), err)
+		}
+		if s.outOfOrderPolicy == OUT_OF_ORDER_DROP && len(want) > 0 && p.Timestamp < want[len(want)-1].Timestamp {
+			continue
+		}
+		want = append(want, p)
+	}
+	s.Flush()
+	if s.outOfOrderPolicy == OUT_OF_ORDER_SORT {
+		sort.SliceStable(want, func(i, j int) bool { return want[i].Timestamp < want[j].Timestamp })
+	}
+	return s.verify(want)
+}
+
+// verify reads s from its current position and compares it with points.
+func (s *Series) verify(points []Point) error {
+	for i, p := range points {
+		timestamp, value, err := s.Read()
+		if err != nil {
+			return fmt.Errorf("point %d of %d: %v", i, len(points), err)
+		}
+		if timestamp != p.Timestamp || math.Float64bits(value) != math.Float64bits(p.Value) {
+			return fmt.Errorf("point %d of %d: got (%d, %v), want (%d, %v)",
+				i, len(points), timestamp, value, p.Timestamp, p.Value)
+		}
+	}
+	if !s.done() {
+		return fmt.Errorf("%d bits left after decoding %d points", s.Bs.NumBits-s.Bs.BitPos, len(points))
+	}
+	return nil
+}
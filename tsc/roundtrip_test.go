@@ -0,0 +1,83 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+const (
+	fuzzChecksum = 1 << iota
+	fuzzConstantRuns
+	fuzzRestarts
+	fuzzDrop
+	fuzzSort
+	fuzzBigJumps
+)
+
+// fuzzPoints reads points of 10 bytes from data: a signed 16-bit distance
+// from the previous timestamp, ignored for the first point, and the bits of
+// the value. With bigJumps the distances are scaled to need more than 32
+// bits of delta of delta.
+func fuzzPoints(data []byte, bigJumps bool) []Point {
+	var points []Point
+	timestamp := uint64(1440583200)
+	for ; len(data) >= 10; data = data[10:] {
+		delta := uint64(int16(binary.BigEndian.Uint16(data)))
+		if bigJumps {
+			delta <<= 40
+		}
+		if len(points) > 0 {
+			timestamp += delta
+		}
+		points = append(points, Point{timestamp, math.Float64frombits(binary.BigEndian.Uint64(data[2:]))})
+	}
+	return points
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	regular := make([]byte, 0, 200)
+	for i := 0; i < 20; i++ {
+		regular = binary.BigEndian.AppendUint16(regular, 60)
+		regular = binary.BigEndian.AppendUint64(regular, math.Float64bits(float64(i/5)))
+	}
+	for flags := uint8(0); flags < 1<<6; flags++ {
+		f.Add(flags%7, flags%5, flags, flags, regular)
+	}
+	f.Add(uint8(0), uint8(0), uint8(fuzzSort), uint8(3), []byte{
+		0, 60, 0, 0, 0, 0, 0, 0, 0, 1,
+		0xff, 0xf0, 0, 0, 0, 0, 0, 0, 0, 2,
+		0, 30, 0x7f, 0xf8, 0, 0, 0, 0, 0, 0,
+	})
+
+	f.Fuzz(func(t *testing.T, timeCodec, valueCodec, flags, n uint8, data []byte) {
+		id := vectorTimeCodecs[int(timeCodec)%len(vectorTimeCodecs)]
+		opts := []Option{
+			WithTimeCodec(id),
+			WithValueCodec(vectorValueCodecs[int(valueCodec)%len(vectorValueCodecs)]),
+		}
+		if flags&fuzzChecksum != 0 {
+			opts = append(opts, WithChecksum())
+		}
+		if flags&fuzzConstantRuns != 0 {
+			opts = append(opts, WithConstantRuns(uint64(n%16)))
+		}
+		if flags&fuzzRestarts != 0 {
+			opts = append(opts, WithRestartInterval(uint64(n)+1))
+		}
+		if flags&fuzzDrop != 0 {
+			opts = append(opts, WithOutOfOrder(OUT_OF_ORDER_DROP, 0))
+		} else if flags&fuzzSort != 0 {
+			opts = append(opts, WithOutOfOrder(OUT_OF_ORDER_SORT, int(n%8)))
+		}
+		// TIME_CODEC_DOD has no escape for such jumps, and restarts store
+		// timestamps in the 32 bits of the first one
+		bigJumps := flags&fuzzBigJumps != 0 && id != TIME_CODEC_DOD &&
+			(id != TIME_CODEC_DOD_ESCAPE || flags&fuzzRestarts == 0)
+		err := RoundTrip(fuzzPoints(data, bigJumps), opts...)
+		if err != nil && !errors.Is(err, ErrOutOfOrder) {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package tsc
+
+import (
+	"errors"
+)
+
+const DEFAULT_MIN_RUN = 16
+
+var errConstantRun = errors.New("Invalid constant run")
+
+/*
+* With WithConstantRuns every entry of the stream starts with a flag bit.
+*
+* flag	entry
+* 0	a point, encoded as usual
+* 1	a run: step and count as sparse deltas, see appendSparseDelta()
+*
+* A run stands for count points repeating the value of the point before
+* it, the first one step after that point and each later one step after
+* the previous. The timestamp codec does not see them.
+ */
+
+// WithConstantRuns makes Series collapse runs of at least minLen points
+// (DEFAULT_MIN_RUN if 0) that repeat the previous value at a constant step
+// into a single record of a few bytes, for counters that sit at the same
+// value for days. Shorter runs are stored point by point. Every other point
+// costs one more bit, and the fast path for runs of unchanged points is
+// disabled. The last run is held back until the value or the step changes;
+// call Flush before reading to store it. Streams written with it must be
+// read with it.
+func WithConstantRuns(minLen uint64) Option {
+	if minLen == 0 {
+		minLen = DEFAULT_MIN_RUN
+	}
+	return func(s *Series) {
+		s.minRun = minLen
+	}
+}
+
+// appendRunPoint holds back a point repeating the last stored value if it
+// continues the current run, or stores the run first if it does not.
+func (s *Series) appendRunPoint(timestamp, value uint64) bool {
+	if s.count == 0 || value != s.lastValueWrite {
+		s.flushRun()
+		return false
+	}
+	if s.runLen > 0 && timestamp-s.runEnd() != s.runStep {
+		s.flushRun()
+	}
+	if s.runLen == 0 {
+		s.runStep = timestamp - s.prevTimeWrite
+	}
+	s.runLen++
+	return true
+}
+
+// runEnd returns the timestamp of the last point of the run held back.
+func (s *Series) runEnd() uint64 {
+	return s.prevTimeWrite + s.runLen*s.runStep
+}
+
+// flushRun stores the run held back, if any.
+func (s *Series) flushRun() {
+	if s.runLen == 0 {
+		return
+	}
+	n, step := s.runLen, s.runStep
+	s.runLen = 0
+	if n < s.minRun {
+		for i := uint64(0); i < n; i++ {
+			s.writePoint(s.prevTimeWrite+step, s.lastValueWrite)
+		}
+		return
+	}
+	s.Bs.AddValueToBitStream(1, 1)
+	appendSparseDelta(&s.Bs, step)
+	appendSparseDelta(&s.Bs, n)
+	s.prevTimeWrite += n * step
+	s.count += n
+}
+
+// readConstantRun reads the flag of the next entry and, for a run, sets up
+// its points to be served by readConstantPoint.
+func (s *Series) readConstantRun() error {
+	flag, err := s.Bs.ReadValueFromBitStream(1)
+	if err != nil || flag == 0 {
+		return err
+	}
+	step, err := readSparseDelta(&s.Bs)
+	if err != nil {
+		return err
+	}
+	n, err := readSparseDelta(&s.Bs)
+	if err != nil {
+		return err
+	}
+	// there is no value to repeat before the first point
+	if n == 0 || s.readIndex == 0 {
+		return errConstantRun
+	}
+	s.constStep, s.constRead = step, n
+	return nil
+}
+
+// readConstantPoint returns the timestamp of the next point of a run found
+// by readConstantRun, its value is unchanged.
+func (s *Series) readConstantPoint() uint64 {
+	s.prevTimeRead += s.constStep
+	s.constRead--
+	s.readIndex++
+	return s.prevTimeRead
+}
@@ -0,0 +1,203 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"github.com/huangaz/tsc/bitUtil"
+)
+
+/*
+* delta		tag	value bits
+* <2^8		0	8
+* <2^16		10	16
+* <2^24		110	24
+* <2^32		1110	32
+* others	1111	64
+ */
+var sparseDeltaBits = []uint64{8, 16, 24, 32, 64}
+
+func appendSparseDelta(bs *bitUtil.BitStream, delta uint64) {
+	last := len(sparseDeltaBits) - 1
+	for n, bits := range sparseDeltaBits {
+		if n == last {
+			bs.AddValueToBitStream((1<<n)-1, uint64(n))
+		} else if delta < 1<<bits {
+			// n ones and a zero
+			bs.AddValueToBitStream((1<<(n+1))-2, uint64(n+1))
+		} else {
+			continue
+		}
+		bs.AddValueToBitStream(delta, bits)
+		return
+	}
+}
+
+func readSparseDelta(bs *bitUtil.BitStream) (uint64, error) {
+	n, err := bs.ReadUnary(uint64(len(sparseDeltaBits) - 1))
+	if err != nil {
+		return 0, err
+	}
+	return bs.ReadValueFromBitStream(sparseDeltaBits[n])
+}
+
+// sparseCodec stores every timestamp as its distance from the previous one,
+// which suits series with a few irregular points a day better than delta
+// of delta, whose jitter buckets they overflow.
+type sparseCodec struct {
+	started  bool
+	prevTime uint64
+}
+
+func (c *sparseCodec) ID() uint8 {
+	return TIME_CODEC_SPARSE
+}
+
+func (c *sparseCodec) Append(bs *bitUtil.BitStream, timestamp uint64) {
+	if !c.started {
+		bs.AddValueToBitStream(timestamp, 64)
+		c.started = true
+	} else {
+		appendSparseDelta(bs, timestamp-c.prevTime)
+	}
+	c.prevTime = timestamp
+}
+
+func (c *sparseCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	if !c.started {
+		timestamp, err := bs.ReadValueFromBitStream(64)
+		if err != nil {
+			return 0, err
+		}
+		c.started = true
+		c.prevTime = timestamp
+		return timestamp, nil
+	}
+	delta, err := readSparseDelta(bs)
+	if err != nil {
+		return 0, err
+	}
+	c.prevTime += delta
+	return c.prevTime, nil
+}
+
+func (c *sparseCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 9)
+	if c.started {
+		data[0] = 1
+	}
+	return binary.BigEndian.AppendUint64(data, c.prevTime), nil
+}
+
+func (c *sparseCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 9 || data[0] > 1 {
+		return errCodecState
+	}
+	c.started = data[0] == 1
+	c.prevTime = binary.BigEndian.Uint64(data[1:])
+	return nil
+}
+
+func (c *sparseCodec) clone() TimeCodec {
+	res := *c
+	return &res
+}
+
+// autoCodec picks between delta of delta and sparse encoding from the
+// distance between the first two timestamps: if it is above sparseGap,
+// delta of delta would spend most bits on escapes, so the series is taken
+// to be sparse. A bit written before the second timestamp records
+// the choice for the decoder. The first timestamp takes 64 bits.
+type autoCodec struct {
+	started   bool
+	decided   bool
+	sparse    bool
+	sparseGap uint64
+	dod       dodCodec
+	sp        sparseCodec
+}
+
+func (c *autoCodec) ID() uint8 {
+	return TIME_CODEC_AUTO
+}
+
+func (c *autoCodec) start(timestamp uint64) {
+	c.started = true
+	c.dod.started, c.dod.prevTime, c.dod.prevDelta = true, timestamp, c.dod.initialDelta()
+	c.sp.started, c.sp.prevTime = true, timestamp
+}
+
+func (c *autoCodec) Append(bs *bitUtil.BitStream, timestamp uint64) {
+	if !c.started {
+		bs.AddValueToBitStream(timestamp, 64)
+		c.start(timestamp)
+		return
+	}
+	if !c.decided {
+		c.decided = true
+		c.sparse = distance(timestamp, c.sp.prevTime) > c.sparseGap
+		if c.sparse {
+			bs.AddValueToBitStream(1, 1)
+		} else {
+			bs.AddValueToBitStream(0, 1)
+		}
+	}
+	if c.sparse {
+		c.sp.Append(bs, timestamp)
+	} else {
+		c.dod.Append(bs, timestamp)
+	}
+}
+
+func (c *autoCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	if !c.started {
+		timestamp, err := bs.ReadValueFromBitStream(64)
+		if err != nil {
+			return 0, err
+		}
+		c.start(timestamp)
+		return timestamp, nil
+	}
+	if !c.decided {
+		sparse, err := bs.ReadValueFromBitStream(1)
+		if err != nil {
+			return 0, err
+		}
+		c.decided = true
+		c.sparse = sparse == 1
+	}
+	if c.sparse {
+		return c.sp.Read(bs)
+	}
+	return c.dod.Read(bs)
+}
+
+func (c *autoCodec) MarshalBinary() ([]byte, error) {
+	var flags byte
+	for i, b := range []bool{c.started, c.decided, c.sparse} {
+		if b {
+			flags |= 1 << i
+		}
+	}
+	dod, _ := c.dod.MarshalBinary()
+	sp, _ := c.sp.MarshalBinary()
+	data := binary.BigEndian.AppendUint64([]byte{flags}, c.sparseGap)
+	return append(append(data, dod...), sp...), nil
+}
+
+func (c *autoCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 1+8+33+9 || data[0] > 7 {
+		return errCodecState
+	}
+	c.started = data[0]&1 != 0
+	c.decided = data[0]&2 != 0
+	c.sparse = data[0]&4 != 0
+	c.sparseGap = binary.BigEndian.Uint64(data[1:])
+	if err := c.dod.UnmarshalBinary(data[9:42]); err != nil {
+		return err
+	}
+	return c.sp.UnmarshalBinary(data[42:])
+}
+
+func (c *autoCodec) clone() TimeCodec {
+	res := *c
+	return &res
+}
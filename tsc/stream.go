@@ -0,0 +1,81 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// STREAM_LOOKAHEAD_BYTES is how much of the stream a Decoder from
+// NewStreamDecoder keeps loaded ahead of the read position, far more than
+// any point takes.
+const STREAM_LOOKAHEAD_BYTES = 512
+
+// WriteTo writes the length of the encoded stream in bits, as 8 bytes big
+// endian, followed by the stream, straight from its pages. Points held
+// back, see Flush, are not included. NewStreamDecoder reads it back.
+func (s *Series) WriteTo(w io.Writer) (int64, error) {
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], s.Bs.NumBits)
+	n, err := w.Write(header[:])
+	total := int64(n)
+	for _, page := range s.Bs.Pages {
+		if err != nil {
+			break
+		}
+		n, err = w.Write(page)
+		total += int64(n)
+	}
+	return total, err
+}
+
+// WriteTo writes the encoded stream, see Series.WriteTo.
+func (e *Encoder) WriteTo(w io.Writer) (int64, error) {
+	return e.s.WriteTo(w)
+}
+
+// NewStreamDecoder returns a Decoder over a stream written by WriteTo with
+// the same codec options as opts. The stream is read from r as points are
+// decoded and dropped once read, so only a few pages are held at a time.
+func NewStreamDecoder(r io.Reader, opts ...Option) (*Decoder, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	d := &Decoder{s: *NewSeries(opts...), src: r, unread: binary.BigEndian.Uint64(header[:])}
+	d.buf = make([]byte, STREAM_LOOKAHEAD_BYTES)
+	return d, nil
+}
+
+// load reads from the source of a stream decoder until the lookahead is
+// filled or the stream ends.
+func (d *Decoder) load() error {
+	if d.src == nil {
+		return nil
+	}
+	bs := &d.s.Bs
+	for d.unread > 0 && bs.NumBits-bs.BitPos < STREAM_LOOKAHEAD_BYTES*8 {
+		bits := d.unread
+		if bits > uint64(len(d.buf))*8 {
+			bits = uint64(len(d.buf)) * 8
+		}
+		buf := d.buf[:(bits+7)/8]
+		if _, err := io.ReadFull(d.src, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		for _, b := range buf {
+			n := uint64(8)
+			if bits < 8 {
+				// the padding of the last byte
+				n = bits
+			}
+			bs.AddValueToBitStream(uint64(b)>>(8-n), n)
+			bits -= n
+			d.unread -= n
+		}
+	}
+	bs.DiscardRead()
+	return nil
+}
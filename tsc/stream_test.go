@@ -0,0 +1,94 @@
+package tsc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func streamSeries() *Series {
+	s := NewSeries()
+	for i := 0; i < 20000; i++ {
+		s.Append(1440583200+60*uint64(i)+uint64(i%3), float64(i%1000)/8)
+	}
+	return s
+}
+
+func TestWriteTo(t *testing.T) {
+	s := streamSeries()
+	var buf bytes.Buffer
+	n, err := s.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) || n != int64(8+s.Bs.Len()) {
+		t.Fatal(n, buf.Len(), err)
+	}
+	if binary.BigEndian.Uint64(buf.Bytes()) != s.Bs.NumBits || !bytes.Equal(buf.Bytes()[8:], s.Bs.Bytes()) {
+		t.Fatal("WriteTo differs from the header and Bytes")
+	}
+}
+
+func TestStreamDecoder(t *testing.T) {
+	s := streamSeries()
+	var buf bytes.Buffer
+	s.WriteTo(&buf)
+
+	d, err := NewStreamDecoder(iotest.OneByteReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !d.Done() {
+		timestamp, value, err := d.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantTimestamp, wantValue, _ := s.Read()
+		if timestamp != wantTimestamp || value != wantValue {
+			t.Fatalf("(%d, %v), want (%d, %v)", timestamp, value, wantTimestamp, wantValue)
+		}
+		held := 0
+		for _, page := range d.s.Bs.Pages {
+			if page != nil {
+				held++
+			}
+		}
+		if held > 2 {
+			t.Fatalf("%d pages held while streaming", held)
+		}
+	}
+	if !s.done() {
+		t.Fatal("stream decoder stopped early")
+	}
+}
+
+func TestStreamDecoderIterator(t *testing.T) {
+	s := streamSeries()
+	var buf bytes.Buffer
+	s.WriteTo(&buf)
+	d, err := NewStreamDecoder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for it := d.Iterator(); it.Next(); n++ {
+	}
+	if n != 20000 {
+		t.Fatal(n)
+	}
+}
+
+func TestStreamDecoderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	streamSeries().WriteTo(&buf)
+	data := buf.Bytes()[:buf.Len()-100]
+	d, err := NewStreamDecoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = d.ReadAppend(nil, nil); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if _, err := NewStreamDecoder(bytes.NewReader(data[:5])); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package tsc
+
+import (
+	"iter"
+	"sync"
+)
+
+// SyncSeries is a Series that one goroutine can append to while others
+// read. Readers work on snapshots, which share the sealed pages of the
+// stream, so only the last page is copied per read and appends are never
+// blocked for longer than that copy.
+type SyncSeries struct {
+	mu sync.Mutex
+	s  Series
+}
+
+// NewSyncSeries returns an empty SyncSeries configured by opts.
+func NewSyncSeries(opts ...Option) *SyncSeries {
+	return &SyncSeries{s: *NewSeries(opts...)}
+}
+
+// Append adds a point and returns the number of bits it took.
+func (c *SyncSeries) Append(timestamp uint64, value float64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s.Append(timestamp, value)
+}
+
+func (c *SyncSeries) AppendChecked(timestamp uint64, value float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s.AppendChecked(timestamp, value)
+}
+
+func (c *SyncSeries) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.s.Flush()
+}
+
+// Snapshot returns a Series holding the points appended so far, for the
+// caller to read on its own.
+func (c *SyncSeries) Snapshot() *Series {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s.Snapshot()
+}
+
+// All iterates over the points appended before it was called.
+func (c *SyncSeries) All(opts ...IterOption) iter.Seq2[uint64, float64] {
+	return c.Snapshot().All(opts...)
+}
+
+func (c *SyncSeries) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s.String()
+}
@@ -0,0 +1,54 @@
+package tsc
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestSyncSeries is meant to be run with -race: readers iterate while the
+// writer crosses several page boundaries.
+func TestSyncSeries(t *testing.T) {
+	const t0, points = 1440583200, 20000
+	c := NewSyncSeries()
+	var wg sync.WaitGroup
+	errs := make(chan string, 4)
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// each snapshot must be a prefix of what is appended
+				i := 0
+				for timestamp, value := range c.All() {
+					if timestamp != t0+60*uint64(i) || value != float64(i*i%1000) {
+						errs <- c.String()
+						return
+					}
+					i++
+				}
+			}
+		}()
+	}
+	for i := 0; i < points; i++ {
+		c.Append(t0+60*uint64(i), float64(i*i%1000))
+	}
+	close(done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("snapshot is not a prefix of the appended points:", err)
+	}
+	n := 0
+	for range c.All() {
+		n++
+	}
+	if n != points {
+		t.Fatalf("%d points, want %d", n, points)
+	}
+}
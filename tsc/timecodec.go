@@ -0,0 +1,485 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"github.com/huangaz/tsc/bitUtil"
+	"math"
+)
+
+const (
+	TIME_CODEC_DOD        = 0
+	TIME_CODEC_FIXED_STEP = 1
+	TIME_CODEC_DOD64      = 2
+	TIME_CODEC_SPARSE     = 3
+	TIME_CODEC_AUTO       = 4
+	TIME_CODEC_PROMETHEUS = 5
+	TIME_CODEC_DOD_ESCAPE = 6
+)
+
+// TimeCodec encodes the timestamps of a Series. A Series uses one instance
+// to append and another to read, each keeping the state of its direction.
+type TimeCodec interface {
+	// ID identifies the codec in encoded data, see RegisterTimeCodec.
+	ID() uint8
+	Append(bs *bitUtil.BitStream, timestamp uint64)
+	Read(bs *bitUtil.BitStream) (uint64, error)
+	// MarshalBinary and UnmarshalBinary snapshot and restore the state.
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+var timeCodecs = newRegistry[TimeCodec]("timestamp")
+
+// RegisterTimeCodec makes a codec available under id to WithTimeCodec.
+// newCodec must return a codec in its initial state. It panics if id is
+// already registered.
+func RegisterTimeCodec(id uint8, newCodec func() TimeCodec) {
+	timeCodecs.register(id, newCodec)
+}
+
+// WithTimeCodec selects the registered codec used for timestamps
+// (TIME_CODEC_DOD by default). It panics if id is not registered.
+func WithTimeCodec(id uint8) Option {
+	timeCodecs.new(id)
+	return func(s *Series) {
+		s.timeCodecID = id
+	}
+}
+
+func (s *Series) timeEncoder() TimeCodec {
+	if s.timeWriter == nil {
+		s.timeWriter = s.newTimeCodec()
+	}
+	return s.timeWriter
+}
+
+func (s *Series) timeDecoder() TimeCodec {
+	if s.timeReader == nil {
+		s.timeReader = s.newTimeCodec()
+	}
+	return s.timeReader
+}
+
+func (s *Series) newTimeCodec() TimeCodec {
+	c := timeCodecs.new(s.timeCodecID)
+	d, ok := c.(*dodCodec)
+	delta, sparseGap := s.timeDefaults()
+	if a, isAuto := c.(*autoCodec); isAuto {
+		d, ok = &a.dod, true
+		a.sparseGap = sparseGap
+	}
+	if ok {
+		d.defaultDelta = int64(delta)
+		d.rebaselineGap = s.rebaselineGap
+	}
+	return c
+}
+
+// dodState returns the delta of delta codec c currently encodes with, if
+// any.
+func dodState(c TimeCodec) *dodCodec {
+	switch c := c.(type) {
+	case *dodCodec:
+		return c
+	case *autoCodec:
+		if c.decided && !c.sparse {
+			return &c.dod
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterTimeCodec(TIME_CODEC_DOD, func() TimeCodec { return &dodCodec{format: &dod32} })
+	RegisterTimeCodec(TIME_CODEC_FIXED_STEP, func() TimeCodec { return &fixedStepCodec{} })
+	RegisterTimeCodec(TIME_CODEC_DOD64, func() TimeCodec { return &dodCodec{format: &dod64} })
+	RegisterTimeCodec(TIME_CODEC_SPARSE, func() TimeCodec { return &sparseCodec{} })
+	RegisterTimeCodec(TIME_CODEC_AUTO, func() TimeCodec { return &autoCodec{dod: dodCodec{format: &dod32Escape}} })
+	RegisterTimeCodec(TIME_CODEC_PROMETHEUS, func() TimeCodec { return &prometheusTimeCodec{} })
+	RegisterTimeCodec(TIME_CODEC_DOD_ESCAPE, func() TimeCodec { return &dodCodec{format: &dod32Escape} })
+}
+
+// dodCodec is the Gorilla encoding: the first timestamp is stored in full
+// and every later one as the difference between its delta and the previous
+// delta.
+type dodCodec struct {
+	format        *dodFormat
+	started       bool
+	prevTime      uint64
+	prevDelta     int64
+	defaultDelta  int64
+	rebaselineGap uint64
+}
+
+type timestampEncoding struct {
+	bitsForValue          uint64
+	controlValue          uint64
+	controlValueBitLength uint64
+}
+
+type timestampDecoding struct {
+	controlValueBitLength uint64
+	bitsForValue          uint64
+	offset                int64
+	nonZero               int64
+}
+
+// dodFormat is a set of delta of delta buckets. Every bucket but an escape,
+// which can only be the last one, must fit in a 64-bit word with its prefix.
+type dodFormat struct {
+	id        uint8
+	firstBits uint64
+	encodings []timestampEncoding
+	// decodings maps the next TIMESTAMP_CONTROL_BITS bits of the stream to
+	// the encoding they start.
+	decodings [1 << TIMESTAMP_CONTROL_BITS]timestampDecoding
+}
+
+/*
+* The original Gorilla buckets. A delta of delta outside of 32 bits wraps
+* around and is read back wrong, use TIME_CODEC_DOD_ESCAPE for series that
+* can jump that far.
+*
+* deltaOfDelta 	tag 	value bits
+* 0		-	1
+* -63,64	10	7
+* -255,256	110	9
+* -2047,2048	1110	12
+* >2048		1111	32
+ */
+var dod32 = newDodFormat(TIME_CODEC_DOD, BITS_FOR_FIRST_TIMESTAMP, []timestampEncoding{
+	{7, 2, 2},
+	{9, 6, 3},
+	{12, 14, 4},
+	{32, 15, 4},
+})
+
+/*
+* dod32Escape splits the last bucket of dod32 to escape to 64 bits, so any
+* jump round-trips, and to rebaseline after a gap, see WithRebaselineGap.
+*
+* deltaOfDelta 	tag 	value bits
+* 0		-	1
+* -63,64	10	7
+* -255,256	110	9
+* -2047,2048	1110	12
+* 32bit		11110	32
+* others	111110	64 (raw two's complement)
+* rebaseline	111111	64 (absolute timestamp, delta reset to the default)
+ */
+var dod32Escape = newDodFormat(TIME_CODEC_DOD_ESCAPE, BITS_FOR_FIRST_TIMESTAMP, []timestampEncoding{
+	{7, 2, 2},
+	{9, 6, 3},
+	{12, 14, 4},
+	{32, 30, 5},
+	{BITS_FOR_DELTA_ESCAPE, 31, 5},
+})
+
+/*
+* dod64 is for millisecond and nanosecond timestamps, whose jitter needs
+* wider buckets, and stores the first timestamp in 64 bits.
+*
+* deltaOfDelta 	tag 	value bits
+* 0		-	1
+* -511,512	10	10
+* -32767,32768	110	16
+* 24bit		1110	24
+* 40bit		11110	40
+* others	111110	64 (raw two's complement)
+* rebaseline	111111	64 (absolute timestamp, delta reset to the default)
+ */
+var dod64 = newDodFormat(TIME_CODEC_DOD64, 64, []timestampEncoding{
+	{10, 2, 2},
+	{16, 6, 3},
+	{24, 14, 4},
+	{40, 30, 5},
+	{BITS_FOR_DELTA_ESCAPE, 31, 5},
+})
+
+func newDodFormat(id uint8, firstBits uint64, encodings []timestampEncoding) dodFormat {
+	f := dodFormat{id: id, firstBits: firstBits, encodings: encodings}
+	for prefix := range f.decodings {
+		// a zero bit at the top means delta of delta is zero
+		d := timestampDecoding{controlValueBitLength: 1}
+		for _, e := range encodings {
+			shift := TIMESTAMP_CONTROL_BITS - e.controlValueBitLength
+			if uint64(prefix)>>shift == e.controlValue {
+				d = timestampDecoding{
+					controlValueBitLength: e.controlValueBitLength,
+					bitsForValue:          e.bitsForValue,
+				}
+				if e.bitsForValue != BITS_FOR_DELTA_ESCAPE {
+					d.offset = 1 << (e.bitsForValue - 1)
+					d.nonZero = 1
+				}
+				break
+			}
+		}
+		f.decodings[prefix] = d
+	}
+	return f
+}
+
+// escape reports whether the last bucket of f is the 64-bit escape, which
+// also carries rebaselines.
+func (f *dodFormat) escape() bool {
+	return f.encodings[len(f.encodings)-1].bitsForValue == BITS_FOR_DELTA_ESCAPE
+}
+
+// timeReach is implemented by time codecs that wrap some timestamps
+// around instead of storing them, see AppendChecked.
+type timeReach interface {
+	// reach returns the largest first timestamp and the largest distance
+	// between consecutive timestamps that read back as written.
+	reach() (maxFirst, maxJump uint64)
+}
+
+func (c *dodCodec) reach() (uint64, uint64) {
+	maxFirst, maxJump := uint64(math.MaxUint64), uint64(math.MaxUint64)
+	if c.format.firstBits < 64 {
+		maxFirst = 1<<c.format.firstBits - 1
+	}
+	if !c.format.escape() {
+		// deltas in [0, maxJump] keep their difference in the last bucket
+		maxJump = 1 << (c.format.encodings[len(c.format.encodings)-1].bitsForValue - 1)
+	}
+	return maxFirst, maxJump
+}
+
+func (c *dodCodec) ID() uint8 {
+	return c.format.id
+}
+
+// timestamp:0-4294967295 for the first one with TIME_CODEC_DOD and
+// TIME_CODEC_DOD_ESCAPE
+func (c *dodCodec) Append(bs *bitUtil.BitStream, timestamp uint64) {
+	if !c.started {
+		//store the first timestamp
+		bs.AddValueToBitStream(timestamp, c.format.firstBits)
+		c.started = true
+		c.prevTime = timestamp
+		c.prevDelta = c.initialDelta()
+		return
+	}
+
+	if c.rebaselineGap > 0 && c.format.escape() && distance(timestamp, c.prevTime) > c.rebaselineGap {
+		// After a long gap the delta is useless for predicting the next
+		// one, so store the timestamp itself and start over.
+		bs.AddValueToBitStream(c.format.encodings[len(c.format.encodings)-1].controlValue, TIMESTAMP_CONTROL_BITS)
+		bs.AddValueToBitStream(1, 1)
+		bs.AddValueToBitStream(timestamp, BITS_FOR_DELTA_ESCAPE)
+		c.prevTime = timestamp
+		c.prevDelta = c.initialDelta()
+		return
+	}
+
+	// wraps around for huge jumps, the decoder wraps back the same way
+	delta := int64(timestamp - c.prevTime)
+	deltaOfDelta := delta - c.prevDelta
+
+	if deltaOfDelta == 0 {
+		c.prevTime = timestamp
+		bs.AddValueToBitStream(0, 1)
+		return
+	}
+
+	rawDeltaOfDelta := deltaOfDelta
+	if deltaOfDelta > 0 {
+		// There are no zeros. Shift by one to fit in x number of bits
+		deltaOfDelta--
+	}
+
+	// uint64 keeps the magnitude of math.MinInt64 intact
+	absValue := uint64(deltaOfDelta)
+	if deltaOfDelta < 0 {
+		absValue = uint64(-deltaOfDelta)
+	}
+
+	for i, e := range c.format.encodings {
+		if e.bitsForValue == BITS_FOR_DELTA_ESCAPE {
+			// Too large for any bucket, store it as is.
+			bs.AddValueToBitStream(e.controlValue, e.controlValueBitLength)
+			bs.AddValueToBitStream(0, 1)
+			bs.AddValueToBitStream(uint64(rawDeltaOfDelta), e.bitsForValue)
+			break
+		}
+		if absValue < (1<<(e.bitsForValue-1)) || i == len(c.format.encodings)-1 {
+			bs.AddValueToBitStream(e.controlValue, e.controlValueBitLength)
+			// Make this value between [0, 2^e.bitsForValue - 1], without
+			// an escape larger ones wrap around
+			encodedValue := uint64(deltaOfDelta+(1<<(e.bitsForValue-1))) & (1<<e.bitsForValue - 1)
+			bs.AddValueToBitStream(encodedValue, e.bitsForValue)
+			break
+		}
+	}
+
+	c.prevTime = timestamp
+	c.prevDelta = delta
+}
+
+func (c *dodCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	if !c.started {
+		if timestamp, err := bs.ReadValueFromBitStream(c.format.firstBits); err != nil {
+			return 0, err
+		} else {
+			c.started = true
+			c.prevDelta = c.initialDelta()
+			c.prevTime = timestamp
+			return timestamp, nil
+		}
+	}
+
+	// The control prefix and its payload always fit in the next word, so
+	// look both up at once instead of reading the prefix bit by bit.
+	word := bs.NextWord()
+	d := c.format.decodings[word>>(64-TIMESTAMP_CONTROL_BITS)]
+	if d.bitsForValue == BITS_FOR_DELTA_ESCAPE {
+		// the payload does not fit in the word with its prefix
+		if err := bs.Advance(d.controlValueBitLength); err != nil {
+			return 0, err
+		}
+		rebaseline, err := bs.ReadValueFromBitStream(1)
+		if err != nil {
+			return 0, err
+		}
+		value, err := bs.ReadValueFromBitStream(d.bitsForValue)
+		if err != nil {
+			return 0, err
+		}
+		if rebaseline == 1 {
+			c.prevTime = value
+			c.prevDelta = c.initialDelta()
+			return value, nil
+		}
+		c.prevDelta += int64(value)
+		c.prevTime += uint64(c.prevDelta)
+		return c.prevTime, nil
+	}
+	if err := bs.Advance(d.controlValueBitLength + d.bitsForValue); err != nil {
+		return 0, err
+	}
+	// For the zero bucket bitsForValue is 0 and the shift yields 0.
+	value := int64((word<<d.controlValueBitLength)>>(64-d.bitsForValue)) - d.offset
+	// [-128,127] becomes [-128,128] without the zero in the middle
+	value += (1 + value>>63) & d.nonZero
+	c.prevDelta += value
+	c.prevTime += uint64(c.prevDelta)
+	return c.prevTime, nil
+}
+
+// initialDelta is the delta assumed before the second timestamp and after
+// a rebaseline.
+func (c *dodCodec) initialDelta() int64 {
+	if c.defaultDelta == 0 {
+		return DEFAULT_DELTA
+	}
+	return c.defaultDelta
+}
+
+func (c *dodCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 33)
+	if c.started {
+		data[0] = 1
+	}
+	data = binary.BigEndian.AppendUint64(data, c.prevTime)
+	data = binary.BigEndian.AppendUint64(data, uint64(c.prevDelta))
+	data = binary.BigEndian.AppendUint64(data, uint64(c.defaultDelta))
+	data = binary.BigEndian.AppendUint64(data, c.rebaselineGap)
+	return data, nil
+}
+
+func (c *dodCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 33 || data[0] > 1 {
+		return errCodecState
+	}
+	c.started = data[0] == 1
+	c.prevTime = binary.BigEndian.Uint64(data[1:])
+	c.prevDelta = int64(binary.BigEndian.Uint64(data[9:]))
+	c.defaultDelta = int64(binary.BigEndian.Uint64(data[17:]))
+	c.rebaselineGap = binary.BigEndian.Uint64(data[25:])
+	return nil
+}
+
+func (c *dodCodec) clone() TimeCodec {
+	res := *c
+	return &res
+}
+
+// fixedStepCodec suits series sampled at a fixed interval: a timestamp one
+// step after the previous one takes a single bit, any other is stored in
+// full and its distance from the previous one becomes the new step.
+type fixedStepCodec struct {
+	started  bool
+	prevTime uint64
+	step     uint64
+}
+
+func (c *fixedStepCodec) ID() uint8 {
+	return TIME_CODEC_FIXED_STEP
+}
+
+func (c *fixedStepCodec) Append(bs *bitUtil.BitStream, timestamp uint64) {
+	if !c.started {
+		bs.AddValueToBitStream(timestamp, 64)
+		c.started = true
+	} else if timestamp-c.prevTime == c.step {
+		bs.AddValueToBitStream(0, 1)
+	} else {
+		bs.AddValueToBitStream(1, 1)
+		bs.AddValueToBitStream(timestamp, 64)
+		c.step = timestamp - c.prevTime
+	}
+	c.prevTime = timestamp
+}
+
+func (c *fixedStepCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	if !c.started {
+		timestamp, err := bs.ReadValueFromBitStream(64)
+		if err != nil {
+			return 0, err
+		}
+		c.started = true
+		c.prevTime = timestamp
+		return timestamp, nil
+	}
+	control, err := bs.ReadValueFromBitStream(1)
+	if err != nil {
+		return 0, err
+	}
+	if control == 0 {
+		c.prevTime += c.step
+		return c.prevTime, nil
+	}
+	timestamp, err := bs.ReadValueFromBitStream(64)
+	if err != nil {
+		return 0, err
+	}
+	c.step = timestamp - c.prevTime
+	c.prevTime = timestamp
+	return timestamp, nil
+}
+
+func (c *fixedStepCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 17)
+	if c.started {
+		data[0] = 1
+	}
+	data = binary.BigEndian.AppendUint64(data, c.prevTime)
+	data = binary.BigEndian.AppendUint64(data, c.step)
+	return data, nil
+}
+
+func (c *fixedStepCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 17 || data[0] > 1 {
+		return errCodecState
+	}
+	c.started = data[0] == 1
+	c.prevTime = binary.BigEndian.Uint64(data[1:])
+	c.step = binary.BigEndian.Uint64(data[9:])
+	return nil
+}
+
+func (c *fixedStepCodec) clone() TimeCodec {
+	res := *c
+	return &res
+}
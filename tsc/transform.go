@@ -0,0 +1,49 @@
+package tsc
+
+import (
+	"math"
+)
+
+// Transform is applied to every point before it is encoded. It returns the
+// point to store, or false to drop it.
+type Transform func(timestamp uint64, value float64) (uint64, float64, bool)
+
+// Scale stores value*multiplier + offset instead of value.
+func Scale(multiplier, offset float64) Transform {
+	return func(timestamp uint64, value float64) (uint64, float64, bool) {
+		return timestamp, value*multiplier + offset, true
+	}
+}
+
+// Clamp limits values to [min, max].
+func Clamp(min, max float64) Transform {
+	return func(timestamp uint64, value float64) (uint64, float64, bool) {
+		return timestamp, math.Max(min, math.Min(max, value)), true
+	}
+}
+
+// DeadBand drops points whose value differs by less than band from the
+// last point it let through.
+func DeadBand(band float64) Transform {
+	var last float64
+	started := false
+	return func(timestamp uint64, value float64) (uint64, float64, bool) {
+		if started && math.Abs(value-last) < band {
+			return timestamp, value, false
+		}
+		started = true
+		last = value
+		return timestamp, value, true
+	}
+}
+
+// applyTransforms runs the point through s.Transforms in order.
+func (s *Series) applyTransforms(timestamp uint64, value float64) (uint64, float64, bool) {
+	for _, t := range s.Transforms {
+		var ok bool
+		if timestamp, value, ok = t(timestamp, value); !ok {
+			return timestamp, value, false
+		}
+	}
+	return timestamp, value, true
+}
@@ -0,0 +1,53 @@
+package tsc
+
+import (
+	"testing"
+)
+
+func TestTransformOrder(t *testing.T) {
+	values := []float64{1, 5, 5.2, 9, 20}
+	tests := []struct {
+		name       string
+		transforms []Transform
+		want       []float64
+	}{
+		{"scale then clamp", []Transform{Scale(10, 1), Clamp(0, 60)}, []float64{11, 51, 53, 60, 60}},
+		{"clamp then scale", []Transform{Clamp(0, 6), Scale(10, 1)}, []float64{11, 51, 53, 61, 61}},
+		// the band is compared with scaled values
+		{"scale then dead band", []Transform{Scale(10, 0), DeadBand(5)}, []float64{10, 50, 90, 200}},
+		{"dead band then scale", []Transform{DeadBand(5), Scale(10, 0)}, []float64{10, 90, 200}},
+	}
+	for _, test := range tests {
+		s := Series{Transforms: test.transforms}
+		for i, v := range values {
+			s.Append(1440583200+60*uint64(i), v)
+		}
+		_, got, err := s.ReadAppend(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: got %v, want %v", test.name, got, test.want)
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Fatalf("%s: got %v, want %v", test.name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestTransformDropStopsChain(t *testing.T) {
+	calls := 0
+	count := func(timestamp uint64, value float64) (uint64, float64, bool) {
+		calls++
+		return timestamp, value, true
+	}
+	s := Series{Transforms: []Transform{DeadBand(1), count}}
+	for i, v := range []float64{1, 1.5, 3, 3.1} {
+		s.Append(1440583200+60*uint64(i), v)
+	}
+	if calls != 2 {
+		t.Fatalf("transforms after a drop ran %d times, want 2", calls)
+	}
+}
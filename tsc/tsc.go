@@ -2,8 +2,14 @@
 package tsc
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/huangaz/tsc/bitUtil"
 	"math"
+	"time"
 )
 
 const (
@@ -13,225 +19,529 @@ const (
 	BLOCK_SIZE_LENGTH_BITS    = 6
 	BLOCK_SIZE_ADJUSTMENT     = 1
 	MAX_LEADING_ZEROS_LENGTH  = (1 << LEADING_ZEROS_LENGTH_BITS) - 1
+	TIMESTAMP_CONTROL_BITS    = 5
+	BITS_FOR_DELTA_ESCAPE     = 64
+	DEFAULT_BATCH_SIZE        = 4096
+	AUTO_SPARSE_GAP           = 3600
 )
 
 type Series struct {
 	Bs bitUtil.BitStream
 
+	// applied in order to every point passed to Append
+	Transforms []Transform
+
+	// see SetValueScale()
+	valueMultiplier float64
+	valueOffset     float64
+	hasValueScale   bool
+	scaleOnRead     bool
+
+	// report-by-exception mode, see SetReportByException()
+	exceptionBand        float64
+	exceptionMaxInterval uint64
+	exceptionEnabled     bool
+	heldTime             uint64
+	heldValue            float64
+	hasHeld              bool
+
+	// see AppendChecked()
+	outOfOrderPolicy int
+	sortWindow       int
+	pending          []Point
+	lastInput        uint64
+	hasInput         bool
+
 	// use for appendTimestamp()
-	prevTimeWrite      uint64
-	prevTimeDeltaWrite int64
+	prevTimeWrite uint64
+	firstTime     uint64
+	count         uint64
+	rebaselineGap uint64
+	defaultDelta  uint64
+	resolution    time.Duration
 
 	// use for readNextTimestamp()
-	prevTimeRead      uint64
-	prevTimeDeltaRead int64
-
-	// use for appendValue()
-	prevValueWrite    float64
-	prevLeadingWrite  uint64
-	prevTrailingWrite uint64
-
-	// use for readNextValue()
-	prevValueRead    float64
-	prevLeadingRead  uint64
-	prevTrailingRead uint64
-}
-
-type timestampEncoding struct {
-	bitsForValue          uint64
-	controlValue          uint64
-	controlValueBitLength uint64
-}
-
-/*
-* deltaOfDelta 	tag 	value bits
-* 0		-	1
-* -63,64	10	7
-* -255,256	110	9
-* -2047,2048	1110	12
-* >2048		1111	32
- */
-var timestampEncodings = []timestampEncoding{
-	{7, 2, 2},
-	{9, 6, 3},
-	{12, 14, 4},
-	{32, 15, 4},
-}
-
-func (s *Series) Append(timestamp uint64, value float64) {
-	s.appendTimestamp(timestamp)
-	s.appendValue(value)
+	prevTimeRead uint64
+	readIndex    uint64
+	strict       bool
+
+	// see WithChecksum()
+	checksum bool
+
+	// samples left in a run of unchanged delta and value, see readRun()
+	runRead uint64
+
+	// see WithRestartInterval()
+	restartInterval uint64
+	lastRestart     uint64
+	restarts        []restartPoint
+	readRestart     uint64
+
+	// see WithConstantRuns()
+	minRun    uint64
+	runLen    uint64
+	runStep   uint64
+	constRead uint64
+	constStep uint64
+
+	// timestamp codecs are created on first use, see timeEncoder()
+	timeCodecID uint8
+	timeWriter  TimeCodec
+	timeReader  TimeCodec
+
+	// value codecs are created on first use, see valueEncoder()
+	valueCodecID   uint8
+	valueWriter    ValueCodec
+	valueReader    ValueCodec
+	lastValueWrite uint64
+	lastValueRead  uint64
 }
 
-func (s *Series) Read() (timestamp uint64, value float64, err error) {
-	if timestamp, err = s.readNextTimestamp(); err != nil {
-		return 0, 0, err
+var errBackwards = errors.New("Timestamp goes backwards")
+
+// Append adds a point and returns the number of bits the call added to the
+// stream, 0 if the point was dropped or held back. Timestamps should not
+// decrease, see AppendChecked for input that may be out of order. Append
+// does not check that the time codec can store the timestamp: with
+// TIME_CODEC_DOD a first timestamp beyond 32 bits or a jump beyond 2^31
+// wraps around and reads back wrong, where AppendChecked returns
+// ErrTimestampRange.
+func (s *Series) Append(timestamp uint64, value float64) uint64 {
+	before := s.Bs.NumBits
+	s.append(timestamp, value)
+	return s.Bs.NumBits - before
+}
+
+func (s *Series) append(timestamp uint64, value float64) {
+	s.lastInput, s.hasInput = timestamp, true
+	if len(s.Transforms) > 0 {
+		var ok bool
+		if timestamp, value, ok = s.applyTransforms(timestamp, value); !ok {
+			return
+		}
 	}
-	if value, err = s.readNextValue(); err != nil {
-		return 0, 0, err
+	if s.exceptionEnabled && s.count > 0 {
+		prevValue := math.Float64frombits(s.lastValueWrite)
+		inBand := math.Abs(value-prevValue) <= s.exceptionBand
+		if inBand && timestamp-s.prevTimeWrite < s.exceptionMaxInterval {
+			s.heldTime, s.heldValue, s.hasHeld = timestamp, value, true
+			return
+		}
+		if !inBand && s.hasHeld {
+			// store where the old level ended so the step is kept
+			s.appendPoint(s.heldTime, s.heldValue)
+		}
+		s.hasHeld = false
 	}
-	return
+	s.appendPoint(timestamp, value)
 }
 
-// timestamp:0-4294967295
-func (s *Series) appendTimestamp(timestamp uint64) {
-	if len(s.Bs.Stream) == 0 {
-		//store the first timestamp
-		s.Bs.AddValueToBitStream(timestamp, BITS_FOR_FIRST_TIMESTAMP)
-		s.prevTimeWrite = timestamp
-		s.prevTimeDeltaWrite = DEFAULT_DELTA
-		return
+// SetReportByException makes Append skip points whose value is within band
+// of the last stored value and whose timestamp is less than maxInterval
+// after it. When a value leaves the band, the last skipped point is stored
+// first, so reading back and holding each value until the next point gives
+// the original step function. Call Flush before reading to store a skipped
+// trailing point.
+func (s *Series) SetReportByException(band float64, maxInterval uint64) {
+	s.exceptionEnabled = true
+	s.exceptionBand = band
+	s.exceptionMaxInterval = maxInterval
+}
+
+// Flush stores the points held back by the sort window of AppendChecked,
+// the last point skipped in report-by-exception mode and the run held back
+// by WithConstantRuns, if any.
+func (s *Series) Flush() {
+	s.flushPending()
+	if s.hasHeld {
+		s.appendPoint(s.heldTime, s.heldValue)
+		s.hasHeld = false
 	}
+	s.flushRun()
+}
 
-	delta := int64(timestamp - s.prevTimeWrite)
-	deltaOfDelta := delta - s.prevTimeDeltaWrite
+func (s *Series) appendPoint(timestamp uint64, value float64) {
+	// value is kept as its raw IEEE 754 bits to avoid conversions per sample
+	bits := math.Float64bits(value)
+	if s.minRun > 0 && s.appendRunPoint(timestamp, bits) {
+		return
+	}
+	s.writePoint(timestamp, bits)
+}
 
-	if deltaOfDelta == 0 {
-		s.prevTimeWrite = timestamp
+func (s *Series) writePoint(timestamp, bits uint64) {
+	if s.restartInterval > 0 {
+		s.restartWrite(timestamp)
+	}
+	if s.minRun > 0 {
 		s.Bs.AddValueToBitStream(0, 1)
-		return
 	}
+	s.appendTimestamp(timestamp)
+	s.valueEncoder().Append(&s.Bs, bits)
+	if s.checksum {
+		s.Bs.AddValueToBitStream(pointChecksum(timestamp, bits), CHECKSUM_BITS)
+	}
+	s.lastValueWrite = bits
+	s.count++
+}
+
+// Count returns the number of points appended, without decoding. Points
+// of a constant run are counted as soon as they are appended; those held
+// back by report-by-exception or the sort window are not until stored.
+func (s *Series) Count() uint64 {
+	return s.count + s.runLen
+}
+
+// FirstTimestamp returns the timestamp of the first point counted by
+// Count, false if there is none.
+func (s *Series) FirstTimestamp() (uint64, bool) {
+	return s.firstTime, s.Count() > 0
+}
 
-	if deltaOfDelta > 0 {
-		// There are no zeros. Shift by one to fit in x number of bits
-		deltaOfDelta--
+// LastTimestamp returns the timestamp of the last point counted by Count,
+// false if there is none.
+func (s *Series) LastTimestamp() (uint64, bool) {
+	if s.runLen > 0 {
+		return s.runEnd(), true
 	}
+	return s.prevTimeWrite, s.count > 0
+}
+
+func (s *Series) String() string {
+	if s.count == 0 {
+		return fmt.Sprintf("Series{bytes: %d, bits: %d, count: 0}", s.Bs.Len(), s.Bs.NumBits)
+	}
+	return fmt.Sprintf("Series{bytes: %d, bits: %d, count: %d, first: %d, last: %d}",
+		s.Bs.Len(), s.Bs.NumBits, s.count, s.firstTime, s.prevTimeWrite)
+}
 
-	absValue := int64(math.Abs(float64(deltaOfDelta)))
+// GoString adds a hex dump of the encoded bytes to String.
+func (s *Series) GoString() string {
+	return s.String() + "\n" + hex.Dump(s.Bs.Bytes())
+}
+
+func (s *Series) Read() (timestamp uint64, value float64, err error) {
+	if !s.strict {
+		return s.read()
+	}
+	index, offset, prevTime := s.readIndex, s.nextOffset(), s.prevTimeRead
+	timestamp, value, err = s.read()
+	if err == nil && index > 0 && timestamp < prevTime {
+		err = errBackwards
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("point %d at bit %d: %w", index, offset, err)
+	}
+	return timestamp, value, nil
+}
 
-	for i := 0; i < 4; i++ {
-		if absValue < (1 << uint(timestampEncodings[i].bitsForValue-1)) {
-			s.Bs.AddValueToBitStream(timestampEncodings[i].controlValue, timestampEncodings[i].controlValueBitLength)
-			// Make this value between [0, 2^timestampEncodings[i].bitsForValue - 1]
-			encodedValue := uint64(deltaOfDelta + (1 << uint(timestampEncodings[i].bitsForValue-1)))
-			s.Bs.AddValueToBitStream(encodedValue, timestampEncodings[i].bitsForValue)
-			break
+func (s *Series) read() (timestamp uint64, value float64, err error) {
+	if s.minRun > 0 {
+		if s.constRead == 0 {
+			if err := s.readConstantRun(); err != nil {
+				return 0, 0, err
+			}
+		}
+		if s.constRead > 0 {
+			return s.readConstantPoint(), s.toFloat(s.lastValueRead), nil
 		}
+	} else if s.runRead > 0 || s.readRun() {
+		return s.readRunPoint(), s.toFloat(s.lastValueRead), nil
+	}
+	s.restartRead()
+	if timestamp, err = s.readNextTimestamp(); err != nil {
+		return 0, 0, err
+	}
+	bits, err := s.valueDecoder().Read(&s.Bs)
+	if err != nil {
+		return 0, 0, err
+	}
+	if s.checksum {
+		if err := s.verifyChecksum(timestamp, bits); err != nil {
+			return 0, 0, err
+		}
+	}
+	s.lastValueRead = bits
+	s.readIndex++
+	return timestamp, s.toFloat(bits), nil
+}
+
+// toFloat converts decoded value bits to the float returned by Read.
+func (s *Series) toFloat(bits uint64) float64 {
+	value := math.Float64frombits(bits)
+	// scaling may not keep the payload of a null
+	if s.scaleOnRead && s.hasValueScale && bits != NULL_VALUE_BITS {
+		value = value*s.valueMultiplier + s.valueOffset
 	}
+	return value
+}
 
-	s.prevTimeWrite = timestamp
-	s.prevTimeDeltaWrite = delta
+// SetValueScale records that stored values v stand for
+// v*multiplier + offset, e.g. 0.001 for values stored in millidegrees and
+// served in degrees. It has no effect on reads unless SetScaleOnRead(true).
+func (s *Series) SetValueScale(multiplier, offset float64) {
+	s.valueMultiplier = multiplier
+	s.valueOffset = offset
+	s.hasValueScale = true
 }
 
-func (s *Series) readNextTimestamp() (uint64, error) {
-	if s.Bs.BitPos == 0 {
-		s.prevTimeDeltaRead = DEFAULT_DELTA
-		if timestamp, err := s.Bs.ReadValueFromBitStream(BITS_FOR_FIRST_TIMESTAMP); err != nil {
-			return 0, err
-		} else {
-			s.prevTimeRead = timestamp
-			return timestamp, nil
+// ValueScale returns the multiplier and offset set by SetValueScale,
+// 1 and 0 if it was never called.
+func (s *Series) ValueScale() (multiplier, offset float64) {
+	if !s.hasValueScale {
+		return 1, 0
+	}
+	return s.valueMultiplier, s.valueOffset
+}
+
+// SetScaleOnRead makes Read, and everything built on it, return values
+// converted with the ValueScale instead of the stored ones.
+func (s *Series) SetScaleOnRead(enable bool) {
+	s.scaleOnRead = enable
+}
+
+// ReadAppend reads the remaining points and appends them to timestamps and
+// values, returning the grown slices. Passing slices from a previous call
+// (resliced to zero length) avoids allocating per query.
+func (s *Series) ReadAppend(timestamps []uint64, values []float64) ([]uint64, []float64, error) {
+	for !s.done() {
+		timestamp, value, err := s.Read()
+		if err != nil {
+			return timestamps, values, err
 		}
+		timestamps = append(timestamps, timestamp)
+		values = append(values, value)
 	}
+	return timestamps, values, nil
+}
 
-	index, err := s.Bs.FindTheFirstZeroBit(4)
-	if err != nil {
-		return 0, err
+// ReadBatches reads the remaining points in batches of at most size points
+// (DEFAULT_BATCH_SIZE if size <= 0) and passes each batch to fn, so memory
+// stays bounded however many points there are. The slices are reused
+// between calls and must not be retained by fn. An error returned by fn
+// stops the read and is returned.
+func (s *Series) ReadBatches(size int, fn func(timestamps []uint64, values []float64) error) error {
+	if size <= 0 {
+		size = DEFAULT_BATCH_SIZE
 	}
-	if index > 0 {
-		// Delta of delta is non zero. Calculate the new delta.
-		// 'index' will be used to find the right length for the value
-		// that is read.
-		index--
-		decodeValue, err := s.Bs.ReadValueFromBitStream(timestampEncodings[index].bitsForValue)
+	timestamps := make([]uint64, 0, size)
+	values := make([]float64, 0, size)
+	for !s.done() {
+		timestamp, value, err := s.Read()
 		if err != nil {
-			return 0, err
+			return err
 		}
-		value := int64(decodeValue)
-		// [0,255] becomes [-128,127]
-		value -= (1 << (timestampEncodings[index].bitsForValue - 1))
-		if value >= 0 {
-			// [-128,127] becomes [-128,128] without the zero in the middle
-			value++
+		timestamps = append(timestamps, timestamp)
+		values = append(values, value)
+		if len(timestamps) == size {
+			if err := fn(timestamps, values); err != nil {
+				return err
+			}
+			timestamps, values = timestamps[:0], values[:0]
 		}
-		s.prevTimeDeltaRead += value
 	}
-	s.prevTimeRead += uint64(s.prevTimeDeltaRead)
-	return s.prevTimeRead, nil
+	if len(timestamps) > 0 {
+		return fn(timestamps, values)
+	}
+	return nil
 }
 
-func (s *Series) appendValue(value float64) {
-	xorWithPrev := math.Float64bits(value) ^ math.Float64bits(s.prevValueWrite)
-	if xorWithPrev == 0 {
-		s.Bs.AddValueToBitStream(0, 1)
-		return
-	} else {
-		s.Bs.AddValueToBitStream(1, 1)
+// ReadRange returns the points with timestamps in [start, end]. Like All,
+// it reads from a Snapshot and leaves the read position of s alone.
+// Decoding stops at the first point after end; points before start still
+// have to be decoded, as every value depends on the previous one.
+func (s *Series) ReadRange(start, end uint64) ([]Point, error) {
+	var points []Point
+	snap := s.Snapshot()
+	snap.Seek(start)
+	r := rangeReader{s: snap, from: start, to: end}
+	for {
+		timestamp, value, ok := r.next()
+		if !ok {
+			return points, r.err
+		}
+		points = append(points, Point{timestamp, value})
 	}
+}
 
-	// calculate the numbers of leading and trailing zeros
-	leading := bitUtil.Clz(xorWithPrev)
-	trailing := bitUtil.Ctz(xorWithPrev)
+// Snapshot returns a Series holding the points appended so far, positioned
+// at the first point for reading. Full pages of the encoded stream are never
+// modified again and are shared with s rather than copied; only the last,
+// still growing page is copied. Appending to s afterwards does not affect
+// the snapshot.
+func (s *Series) Snapshot() *Series {
+	snap := *s
+	snap.Bs = s.Bs.Snapshot()
+	if s.valueWriter != nil {
+		snap.valueWriter = valueCodecs.clone(s.valueWriter)
+	}
+	if s.timeWriter != nil {
+		snap.timeWriter = timeCodecs.clone(s.timeWriter)
+	}
+	snap.pending = append([]Point(nil), s.pending...)
+	snap.restarts = append([]restartPoint(nil), s.restarts...)
+	snap.resetRead()
+	return &snap
+}
 
-	if leading > MAX_LEADING_ZEROS_LENGTH {
-		leading = MAX_LEADING_ZEROS_LENGTH
+// Reset empties s to be written again, keeping its options, transforms
+// and the pages of its stream, so a new block needs no new pages. Snapshots
+// of s, including those behind All and Iterator, must no longer be in use.
+func (s *Series) Reset() {
+	bs := s.Bs
+	bs.Reset()
+	*s = Series{
+		Bs:                   bs,
+		Transforms:           s.Transforms,
+		valueMultiplier:      s.valueMultiplier,
+		valueOffset:          s.valueOffset,
+		hasValueScale:        s.hasValueScale,
+		scaleOnRead:          s.scaleOnRead,
+		exceptionBand:        s.exceptionBand,
+		exceptionMaxInterval: s.exceptionMaxInterval,
+		exceptionEnabled:     s.exceptionEnabled,
+		outOfOrderPolicy:     s.outOfOrderPolicy,
+		sortWindow:           s.sortWindow,
+		pending:              s.pending[:0],
+		rebaselineGap:        s.rebaselineGap,
+		defaultDelta:         s.defaultDelta,
+		resolution:           s.resolution,
+		strict:               s.strict,
+		checksum:             s.checksum,
+		minRun:               s.minRun,
+		restartInterval:      s.restartInterval,
+		restarts:             s.restarts[:0],
+		timeCodecID:          s.timeCodecID,
+		valueCodecID:         s.valueCodecID,
 	}
+}
 
-	blockSize := 64 - leading - trailing
-	expectedSize := LEADING_ZEROS_LENGTH_BITS + BLOCK_SIZE_LENGTH_BITS + blockSize
-	prevBolckInformationSize := 64 - s.prevLeadingWrite - s.prevTrailingWrite
+// Release is Reset, handing the pages of the stream to a pool shared by
+// all series instead of keeping them, for series that are done with.
+func (s *Series) Release() {
+	s.Reset()
+	s.Bs.Release()
+}
 
-	if leading >= s.prevLeadingWrite && trailing >= s.prevTrailingWrite && prevBolckInformationSize < expectedSize {
-		//Control bit for using previous block information.
-		s.Bs.AddValueToBitStream(1, 1)
-		blockValue := xorWithPrev >> s.prevTrailingWrite
-		s.Bs.AddValueToBitStream(blockValue, prevBolckInformationSize)
-	} else {
-		//Control bit for not using previous block information.
-		s.Bs.AddValueToBitStream(0, 1)
-		s.Bs.AddValueToBitStream(leading, LEADING_ZEROS_LENGTH_BITS)
-		//To fit in 6 bits. There will never be a zero size block
-		s.Bs.AddValueToBitStream(blockSize-BLOCK_SIZE_ADJUSTMENT, BLOCK_SIZE_LENGTH_BITS)
-		blockValue := xorWithPrev >> trailing
-		s.Bs.AddValueToBitStream(blockValue, blockSize)
-		s.prevLeadingWrite = leading
-		s.prevTrailingWrite = trailing
+// Hash returns a SHA-256 digest of the encoded stream, so identical chunks
+// (such as series that flatline at the same value over the same times) can
+// be detected without comparing their bytes.
+func (s *Series) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	var numBits [8]byte
+	binary.BigEndian.PutUint64(numBits[:], s.Bs.NumBits)
+	h.Write(numBits[:])
+	for _, page := range s.Bs.Pages {
+		h.Write(page)
 	}
-	s.prevValueWrite = value
+	var res [sha256.Size]byte
+	h.Sum(res[:0])
+	return res
 }
 
-func (s *Series) readNextValue() (float64, error) {
-	nonZeroValue, err := s.Bs.ReadValueFromBitStream(1)
-	if err != nil {
-		return 0, err
+// resetRead moves the read position back to the first point.
+func (s *Series) resetRead() {
+	s.Bs.BitPos = 0
+	s.timeReader, s.prevTimeRead, s.runRead, s.readIndex = nil, 0, 0, 0
+	s.constRead, s.constStep, s.readRestart = 0, 0, 0
+	s.valueReader, s.lastValueRead = nil, 0
+}
+
+// done reports whether every point written so far has been read.
+func (s *Series) done() bool {
+	return s.runRead == 0 && s.constRead == 0 && s.Bs.BitPos >= s.Bs.NumBits
+}
+
+// readRun detects a run of samples whose delta of delta and value xor are
+// both zero, i.e. consecutive "00" pairs, and consumes the whole run at once.
+// The samples are then served from runRead without touching the bitstream.
+func (s *Series) readRun() bool {
+	// other codecs may not encode an unchanged delta or value as a single
+	// zero bit, and checksums separate the pairs
+	d := dodState(s.timeReader)
+	if d == nil || !d.started || s.valueCodecID != VALUE_CODEC_XOR || s.checksum {
+		return false
+	}
+	word := s.Bs.NextWord()
+	zeros := uint64(64)
+	if word != 0 {
+		zeros = bitUtil.Clz(word)
+	}
+	if remaining := s.Bs.NumBits - s.Bs.BitPos; zeros > remaining {
+		zeros = remaining
+	}
+	pairs := zeros / 2
+	if k := s.restartInterval; k > 0 {
+		// the point of a restart is not a pair
+		if since := s.readIndex - s.readRestart; since >= k {
+			pairs = 0
+		} else if pairs > k-since {
+			pairs = k - since
+		}
+	}
+	if pairs == 0 {
+		return false
+	}
+	s.Bs.BitPos += 2 * pairs
+	s.runRead = pairs
+	return true
+}
+
+// readRunPoint returns the timestamp of the next point of a run found by
+// readRun, its value is unchanged.
+func (s *Series) readRunPoint() uint64 {
+	d := dodState(s.timeReader)
+	d.prevTime += uint64(d.prevDelta)
+	s.runRead--
+	s.readIndex++
+	s.prevTimeRead = d.prevTime
+	return d.prevTime
+}
+
+// nextOffset returns the bit offset at which the next point to read
+// starts. Points of a run are consumed together but take two bits each.
+func (s *Series) nextOffset() uint64 {
+	return s.Bs.BitPos - 2*s.runRead
+}
+
+// skipRun drops the points left in the run found by readRun or
+// readConstantRun, which all repeat the last value read.
+func (s *Series) skipRun() {
+	if s.constRead > 0 {
+		s.prevTimeRead += s.constRead * s.constStep
+		s.readIndex += s.constRead
+		s.constRead = 0
+	}
+	if s.runRead == 0 {
+		return
 	}
+	d := dodState(s.timeReader)
+	d.prevTime += s.runRead * uint64(d.prevDelta)
+	s.readIndex += s.runRead
+	s.prevTimeRead = d.prevTime
+	s.runRead = 0
+}
 
-	if nonZeroValue == 0 {
-		return s.prevValueRead, nil
+func (s *Series) appendTimestamp(timestamp uint64) {
+	s.timeEncoder().Append(&s.Bs, timestamp)
+	if s.count == 0 {
+		s.firstTime = timestamp
 	}
+	s.prevTimeWrite = timestamp
+}
 
-	usePrevBlockInformation, err := s.Bs.ReadValueFromBitStream(1)
+func (s *Series) readNextTimestamp() (uint64, error) {
+	timestamp, err := s.timeDecoder().Read(&s.Bs)
 	if err != nil {
 		return 0, err
 	}
+	s.prevTimeRead = timestamp
+	return timestamp, nil
+}
 
-	var xorValue uint64
-	if usePrevBlockInformation == 1 {
-		xorValue, err = s.Bs.ReadValueFromBitStream(64 - s.prevLeadingRead - s.prevTrailingRead)
-		if err != nil {
-			return 0, err
-		}
-		xorValue <<= s.prevTrailingRead
-	} else {
-		leading, err := s.Bs.ReadValueFromBitStream(LEADING_ZEROS_LENGTH_BITS)
-		if err != nil {
-			return 0, err
-		}
-		blockSize, err := s.Bs.ReadValueFromBitStream(BLOCK_SIZE_LENGTH_BITS)
-		if err != nil {
-			return 0, err
-		}
-		blockSize += BLOCK_SIZE_ADJUSTMENT
-		s.prevTrailingRead = 64 - leading - blockSize
-		xorValue, err = s.Bs.ReadValueFromBitStream(blockSize)
-		if err != nil {
-			return 0, err
-		}
-		xorValue <<= s.prevTrailingRead
-		s.prevLeadingRead = leading
+func distance(a, b uint64) uint64 {
+	if a > b {
+		return a - b
 	}
-
-	value := math.Float64frombits(xorValue ^ math.Float64bits(s.prevValueRead))
-	s.prevValueRead = value
-	return value, nil
+	return b - a
 }
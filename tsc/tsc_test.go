@@ -0,0 +1,53 @@
+package tsc
+
+import (
+	"math"
+	"testing"
+)
+
+const benchmarkPoints = 4096
+
+// benchmarkSeries has a regular interval and a value changing on every
+// point, so each one goes through the xor of the previous value.
+func benchmarkSeries() ([]uint64, []float64) {
+	timestamps := make([]uint64, benchmarkPoints)
+	values := make([]float64, benchmarkPoints)
+	for i := range timestamps {
+		timestamps[i] = 1440583200 + 60*uint64(i)
+		values[i] = 100 + 10*math.Sin(float64(i)/16)
+	}
+	return timestamps, values
+}
+
+func BenchmarkAppend(b *testing.B) {
+	timestamps, values := benchmarkSeries()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var s Series
+		for i := range timestamps {
+			s.Append(timestamps[i], values[i])
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchmarkPoints), "ns/point")
+}
+
+func BenchmarkRead(b *testing.B) {
+	timestamps, values := benchmarkSeries()
+	var s Series
+	for i := range timestamps {
+		s.Append(timestamps[i], values[i])
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// a copy starts reading from the first point
+		r := s
+		for i := 0; i < benchmarkPoints; i++ {
+			if _, _, err := r.Read(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchmarkPoints), "ns/point")
+}
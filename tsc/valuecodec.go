@@ -0,0 +1,194 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/huangaz/tsc/bitUtil"
+)
+
+const (
+	VALUE_CODEC_XOR        = 0
+	VALUE_CODEC_DECIMAL    = 1
+	VALUE_CODEC_CHIMP      = 2
+	VALUE_CODEC_CHIMP128   = 3
+	VALUE_CODEC_PROMETHEUS = 4
+)
+
+var errXorBlock = errors.New("Leading zeros and block size exceed the value width")
+
+// ValueCodec encodes the values of a Series. A Series uses one instance to
+// append and another to read, each keeping the state of its direction.
+// Values are passed as their IEEE 754 bits.
+type ValueCodec interface {
+	// ID identifies the codec in encoded data, see RegisterValueCodec.
+	ID() uint8
+	Append(bs *bitUtil.BitStream, value uint64)
+	Read(bs *bitUtil.BitStream) (uint64, error)
+	// MarshalBinary and UnmarshalBinary snapshot and restore the state.
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+var valueCodecs = newRegistry[ValueCodec]("value")
+
+// RegisterValueCodec makes a codec available under id to WithValueCodec.
+// newCodec must return a codec in its initial state. It panics if id is
+// already registered.
+func RegisterValueCodec(id uint8, newCodec func() ValueCodec) {
+	valueCodecs.register(id, newCodec)
+}
+
+// WithValueCodec selects the registered codec used for values
+// (VALUE_CODEC_XOR by default). It panics if id is not registered.
+func WithValueCodec(id uint8) Option {
+	valueCodecs.new(id)
+	return func(s *Series) {
+		s.valueCodecID = id
+	}
+}
+
+func (s *Series) valueEncoder() ValueCodec {
+	if s.valueWriter == nil {
+		s.valueWriter = valueCodecs.new(s.valueCodecID)
+	}
+	return s.valueWriter
+}
+
+func (s *Series) valueDecoder() ValueCodec {
+	if s.valueReader == nil {
+		s.valueReader = valueCodecs.new(s.valueCodecID)
+	}
+	return s.valueReader
+}
+
+func init() {
+	RegisterValueCodec(VALUE_CODEC_XOR, func() ValueCodec { return &xorCodec{} })
+	RegisterValueCodec(VALUE_CODEC_DECIMAL, func() ValueCodec { return &decimalCodec{} })
+	RegisterValueCodec(VALUE_CODEC_CHIMP, func() ValueCodec { return &chimpCodec{} })
+	RegisterValueCodec(VALUE_CODEC_CHIMP128, func() ValueCodec { return &chimp128Codec{} })
+	RegisterValueCodec(VALUE_CODEC_PROMETHEUS, func() ValueCodec { return &prometheusValueCodec{} })
+}
+
+// xorCodec is the Gorilla encoding: each value is xored with the previous
+// one and only the meaningful bits of the result are stored.
+type xorCodec struct {
+	prev         uint64
+	prevLeading  uint64
+	prevTrailing uint64
+}
+
+func (c *xorCodec) ID() uint8 {
+	return VALUE_CODEC_XOR
+}
+
+func (c *xorCodec) Append(bs *bitUtil.BitStream, value uint64) {
+	xorWithPrev := value ^ c.prev
+	if xorWithPrev == 0 {
+		bs.AddValueToBitStream(0, 1)
+		return
+	} else {
+		bs.AddValueToBitStream(1, 1)
+	}
+
+	// calculate the numbers of leading and trailing zeros
+	leading := bitUtil.Clz(xorWithPrev)
+	trailing := bitUtil.Ctz(xorWithPrev)
+
+	if leading > MAX_LEADING_ZEROS_LENGTH {
+		leading = MAX_LEADING_ZEROS_LENGTH
+	}
+
+	blockSize := 64 - leading - trailing
+	expectedSize := LEADING_ZEROS_LENGTH_BITS + BLOCK_SIZE_LENGTH_BITS + blockSize
+	prevBolckInformationSize := 64 - c.prevLeading - c.prevTrailing
+
+	if leading >= c.prevLeading && trailing >= c.prevTrailing && prevBolckInformationSize < expectedSize {
+		//Control bit for using previous block information.
+		bs.AddValueToBitStream(1, 1)
+		blockValue := xorWithPrev >> c.prevTrailing
+		bs.AddValueToBitStream(blockValue, prevBolckInformationSize)
+	} else {
+		//Control bit for not using previous block information.
+		bs.AddValueToBitStream(0, 1)
+		bs.AddValueToBitStream(leading, LEADING_ZEROS_LENGTH_BITS)
+		//To fit in 6 bits. There will never be a zero size block
+		bs.AddValueToBitStream(blockSize-BLOCK_SIZE_ADJUSTMENT, BLOCK_SIZE_LENGTH_BITS)
+		blockValue := xorWithPrev >> trailing
+		bs.AddValueToBitStream(blockValue, blockSize)
+		c.prevLeading = leading
+		c.prevTrailing = trailing
+	}
+	c.prev = value
+}
+
+func (c *xorCodec) Read(bs *bitUtil.BitStream) (uint64, error) {
+	nonZeroValue, err := bs.ReadValueFromBitStream(1)
+	if err != nil {
+		return 0, err
+	}
+
+	if nonZeroValue == 0 {
+		return c.prev, nil
+	}
+
+	usePrevBlockInformation, err := bs.ReadValueFromBitStream(1)
+	if err != nil {
+		return 0, err
+	}
+
+	var xorValue uint64
+	if usePrevBlockInformation == 1 {
+		xorValue, err = bs.ReadValueFromBitStream(64 - c.prevLeading - c.prevTrailing)
+		if err != nil {
+			return 0, err
+		}
+		xorValue <<= c.prevTrailing
+	} else {
+		leading, err := bs.ReadValueFromBitStream(LEADING_ZEROS_LENGTH_BITS)
+		if err != nil {
+			return 0, err
+		}
+		blockSize, err := bs.ReadValueFromBitStream(BLOCK_SIZE_LENGTH_BITS)
+		if err != nil {
+			return 0, err
+		}
+		blockSize += BLOCK_SIZE_ADJUSTMENT
+		if leading+blockSize > 64 {
+			return 0, errXorBlock
+		}
+		c.prevTrailing = 64 - leading - blockSize
+		xorValue, err = bs.ReadValueFromBitStream(blockSize)
+		if err != nil {
+			return 0, err
+		}
+		xorValue <<= c.prevTrailing
+		c.prevLeading = leading
+	}
+
+	value := xorValue ^ c.prev
+	c.prev = value
+	return value, nil
+}
+
+func (c *xorCodec) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 24)
+	data = binary.BigEndian.AppendUint64(data, c.prev)
+	data = binary.BigEndian.AppendUint64(data, c.prevLeading)
+	data = binary.BigEndian.AppendUint64(data, c.prevTrailing)
+	return data, nil
+}
+
+func (c *xorCodec) UnmarshalBinary(data []byte) error {
+	if len(data) != 24 {
+		return errCodecState
+	}
+	c.prev = binary.BigEndian.Uint64(data)
+	c.prevLeading = binary.BigEndian.Uint64(data[8:])
+	c.prevTrailing = binary.BigEndian.Uint64(data[16:])
+	return nil
+}
+
+func (c *xorCodec) clone() ValueCodec {
+	res := *c
+	return &res
+}
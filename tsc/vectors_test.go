@@ -0,0 +1,69 @@
+package tsc
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+const goldenVectors = "testdata/vectors.golden"
+
+var update = flag.Bool("update", false, "rewrite "+goldenVectors)
+
+func vectorLine(v TestVector) string {
+	return fmt.Sprintf("%s %d %d %d %d %s", v.Name, v.TimeCodec, v.ValueCodec, v.Version, v.NumBits, hex.EncodeToString(v.Encoded))
+}
+
+func TestVectorsGolden(t *testing.T) {
+	var lines []string
+	for _, v := range TestVectors() {
+		lines = append(lines, vectorLine(v))
+	}
+	if *update {
+		if err := os.WriteFile(goldenVectors, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(goldenVectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var golden []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		golden = append(golden, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(golden) != len(lines) {
+		t.Fatalf("%d vectors, %d in %s", len(lines), len(golden), goldenVectors)
+	}
+	for i := range lines {
+		if lines[i] != golden[i] {
+			t.Errorf("encoding changed, bump FORMAT_VERSION and run with -update\ngot  %s\nwant %s", lines[i], golden[i])
+		}
+	}
+}
+
+func TestVectorsDecode(t *testing.T) {
+	for _, v := range TestVectors() {
+		d, err := NewDecoder(v.Encoded, v.NumBits, WithTimeCodec(v.TimeCodec), WithValueCodec(v.ValueCodec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range v.Timestamps {
+			timestamp, value, err := d.Read()
+			if err != nil || timestamp != v.Timestamps[i] || math.Float64bits(value) != math.Float64bits(v.Values[i]) {
+				t.Fatalf("%s %d/%d: point %d is %d %v %v", v.Name, v.TimeCodec, v.ValueCodec, i, timestamp, value, err)
+			}
+		}
+	}
+}